@@ -0,0 +1,736 @@
+package gofirefox
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/unikiosk/go-firefox/prefs"
+)
+
+// bidi drives firefox over WebDriver BiDi
+// (https://w3c.github.io/webdriver-bidi/). Tabs are the top-level browsing
+// contexts.
+type bidi struct {
+	logger *slog.Logger
+
+	sync.Mutex
+	current    *conn             // connection to the running firefox
+	intercepts map[string]string // network intercept IDs by browsing context
+}
+
+func newBiDi(logger *slog.Logger) *bidi {
+	return &bidi{current: newConn(0), logger: logger, intercepts: map[string]string{}}
+}
+
+func (p *bidi) conn() *conn {
+	p.Lock()
+	defer p.Unlock()
+	return p.current
+}
+
+// bidiMessage is a command result, an error or an event.
+type bidiMessage struct {
+	Type    string          `json:"type"`
+	ID      int             `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   string          `json:"error"`
+	Message string          `json:"message"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// bidiEvents are the events the session subscribes to.
+var bidiEvents = []string{
+	"browsingContext.contextCreated",
+	"browsingContext.contextDestroyed",
+	"browsingContext.navigationStarted",
+	"browsingContext.domContentLoaded",
+	"browsingContext.load",
+	"browsingContext.fragmentNavigated",
+	"browsingContext.navigationFailed",
+	"browsingContext.navigationAborted",
+	"script.realmCreated",
+	"log.entryAdded",
+	"network.beforeRequestSent",
+	"network.responseCompleted",
+	"network.fetchError",
+}
+
+func (p *bidi) Prefs() []prefs.Pref {
+	return []prefs.Pref{{Name: "remote.active-protocols", Value: 1}}
+}
+
+func (p *bidi) Endpoint() *regexp.Regexp {
+	return regexp.MustCompile(`^WebDriver BiDi listening on (ws://.*?)\r?\n$`)
+}
+
+func (p *bidi) Connect(ctx context.Context, url string, events ProtocolEvents) (string, error) {
+	conn := newConn(0)
+	p.Lock()
+	p.current = conn
+	p.intercepts = map[string]string{}
+	p.Unlock()
+	if err := conn.dial(strings.TrimSuffix(url, "/") + "/session"); err != nil {
+		return "", err
+	}
+	go p.readLoop(conn, events)
+
+	if _, err := p.send(ctx, "session.new", h{"capabilities": h{}}); err != nil {
+		return "", err
+	}
+	if _, err := p.send(ctx, "session.subscribe", h{"events": bidiEvents}); err != nil {
+		return "", err
+	}
+	// the window may not be open yet
+	for {
+		raw, err := p.send(ctx, "browsingContext.getTree", h{"maxDepth": 0})
+		if err != nil {
+			return "", err
+		}
+		tree := struct {
+			Contexts []struct {
+				Context string `json:"context"`
+			} `json:"contexts"`
+		}{}
+		if err := json.Unmarshal(raw, &tree); err != nil {
+			return "", err
+		}
+		if len(tree.Contexts) > 0 {
+			return tree.Contexts[0].Context, nil
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func (p *bidi) readLoop(conn *conn, events ProtocolEvents) {
+	defer events.Disconnected()
+	defer conn.close()
+	for {
+		m := bidiMessage{}
+		if err := conn.receive(&m); err != nil {
+			return
+		}
+
+		switch m.Type {
+		case "success":
+			conn.complete(m.ID, m.Result, nil)
+		case "error":
+			conn.complete(m.ID, nil, &ProtocolError{Message: fmt.Sprintf("%s: %s", m.Error, m.Message)})
+		case "event":
+			p.handleEvent(events, m.Method, m.Params)
+		}
+	}
+}
+
+// bidiSource is where the log entry or realm comes from.
+type bidiSource struct {
+	Realm   string `json:"realm"`
+	Context string `json:"context"`
+}
+
+func (p *bidi) handleEvent(events ProtocolEvents, method string, raw json.RawMessage) {
+	origin := struct {
+		Context string     `json:"context"`
+		Source  bidiSource `json:"source"`
+	}{}
+	json.Unmarshal(raw, &origin)
+	if origin.Context == "" {
+		// log entries
+		origin.Context = origin.Source.Context
+	}
+	events.Event(origin.Context, method, raw)
+	p.handleNavigationEvent(events, method, raw)
+
+	switch method {
+	case "browsingContext.contextCreated", "browsingContext.load", "browsingContext.fragmentNavigated":
+		params := struct {
+			Context        string  `json:"context"`
+			URL            string  `json:"url"`
+			Parent         *string `json:"parent"`
+			OriginalOpener *string `json:"originalOpener"`
+		}{}
+		json.Unmarshal(raw, &params)
+		if params.Parent != nil {
+			break
+		}
+		if method == "browsingContext.contextCreated" {
+			opener := ""
+			if params.OriginalOpener != nil {
+				opener = *params.OriginalOpener
+			}
+			events.TabOpened(params.Context, opener, params.URL)
+		}
+		events.TabChanged(params.Context, params.URL)
+	case "browsingContext.contextDestroyed":
+		params := struct {
+			Context string  `json:"context"`
+			Parent  *string `json:"parent"`
+		}{}
+		json.Unmarshal(raw, &params)
+		if params.Parent == nil {
+			events.TabClosed(params.Context)
+		}
+	case "script.realmCreated":
+		params := struct {
+			Type string `json:"type"`
+			bidiSource
+			Sandbox string `json:"sandbox"`
+		}{}
+		json.Unmarshal(raw, &params)
+		if params.Type == "window" && params.Sandbox == "" {
+			events.RealmCreated(params.Context, params.Realm)
+		}
+	case "log.entryAdded":
+		entry := struct {
+			Type       string          `json:"type"`
+			Text       string          `json:"text"`
+			Timestamp  float64         `json:"timestamp"`
+			Method     string          `json:"method"`
+			Args       []bidiValue     `json:"args"`
+			Source     bidiSource      `json:"source"`
+			StackTrace *bidiStackTrace `json:"stackTrace"`
+		}{}
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			p.logger.Warn("invalid log entry", "err", err)
+			return
+		}
+		if entry.Source.Context == "" {
+			// e.g. workers
+			return
+		}
+		switch entry.Type {
+		case "console":
+			msg := ConsoleMessage{Type: entry.Method, Text: entry.Text, Timestamp: epochMillis(entry.Timestamp)}
+			for _, arg := range entry.Args {
+				msg.Args = append(msg.Args, arg.toValue())
+			}
+			if entry.StackTrace != nil {
+				msg.setCallFrames(entry.StackTrace.CallFrames)
+			}
+			events.Console(entry.Source.Context, entry.Source.Realm, msg)
+		case "javascript":
+			e := &JSException{Text: entry.Text}
+			e.setStackTrace(entry.StackTrace)
+			events.Exception(entry.Source.Context, e)
+		}
+	case "network.beforeRequestSent":
+		params := struct {
+			Context    string  `json:"context"`
+			IsBlocked  bool    `json:"isBlocked"`
+			Navigation *string `json:"navigation"`
+			Request    struct {
+				Request       string       `json:"request"`
+				URL           string       `json:"url"`
+				Method        string       `json:"method"`
+				Headers       []bidiHeader `json:"headers"`
+				Destination   string       `json:"destination"`
+				InitiatorType string       `json:"initiatorType"`
+			} `json:"request"`
+		}{}
+		if err := json.Unmarshal(raw, &params); err != nil || !params.IsBlocked {
+			return
+		}
+		headers := map[string]string{}
+		for _, header := range params.Request.Headers {
+			headers[header.Name] = header.Value.Value
+		}
+		destination := params.Request.Destination
+		events.RequestPaused(params.Context, &InterceptedRequest{
+			URL:          params.Request.URL,
+			Method:       params.Request.Method,
+			Headers:      headers,
+			ResourceType: bidiResourceType(destination, params.Request.InitiatorType),
+			id:           params.Request.Request,
+			// the navigations of the frames are in their own contexts
+			navigation: params.Navigation != nil && destination != "iframe" && destination != "frame",
+		})
+	}
+}
+
+// bidiHeader is a mirror of the network.Header.
+type bidiHeader struct {
+	Name  string `json:"name"`
+	Value struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	} `json:"value"`
+}
+
+// bidiHeaders returns the headers sorted by name, or nil if headers is nil.
+func bidiHeaders(headers map[string]string) []bidiHeader {
+	if headers == nil {
+		return nil
+	}
+	entries := []bidiHeader{}
+	for name, value := range headers {
+		header := bidiHeader{Name: name}
+		header.Value.Type, header.Value.Value = "string", value
+		entries = append(entries, header)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries
+}
+
+// bidiResourceType returns the resource type of the request destination
+// (https://fetch.spec.whatwg.org/#concept-request-destination) and initiator.
+func bidiResourceType(destination, initiatorType string) ResourceType {
+	switch destination {
+	case "document", "iframe", "frame":
+		return ResourceDocument
+	case "style":
+		return ResourceStylesheet
+	case "image":
+		return ResourceImage
+	case "audio", "video", "track":
+		return ResourceMedia
+	case "font":
+		return ResourceFont
+	case "script":
+		return ResourceScript
+	}
+	switch initiatorType {
+	case "xmlhttprequest":
+		return ResourceXHR
+	case "fetch":
+		return ResourceFetch
+	}
+	return ResourceOther
+}
+
+// handleNavigationEvent reports the navigations and requests of the browsing
+// contexts. Those of the frames are reported too, and ignored as they are not
+// tabs.
+func (p *bidi) handleNavigationEvent(events ProtocolEvents, method string, raw json.RawMessage) {
+	if !strings.HasPrefix(method, "browsingContext.") && !strings.HasPrefix(method, "network.") {
+		return
+	}
+	params := struct {
+		Context    string  `json:"context"`
+		Navigation *string `json:"navigation"`
+		URL        string  `json:"url"`
+		Request    struct {
+			Request string `json:"request"`
+			URL     string `json:"url"`
+		} `json:"request"`
+		Response struct {
+			URL        string `json:"url"`
+			Status     int    `json:"status"`
+			StatusText string `json:"statusText"`
+		} `json:"response"`
+		ErrorText string `json:"errorText"`
+	}{}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return
+	}
+	navigation := ""
+	if params.Navigation != nil {
+		navigation = *params.Navigation
+	}
+
+	switch method {
+	case "browsingContext.navigationStarted":
+		events.NavigationStarted(params.Context, navigation, params.URL)
+	case "browsingContext.domContentLoaded":
+		events.LoadStateReached(params.Context, LoadStateDOMContentLoaded)
+	case "browsingContext.load":
+		events.LoadStateReached(params.Context, LoadStateLoad)
+	case "browsingContext.navigationFailed":
+		events.NavigationFailed(params.Context, navigation, &NavigationError{URL: params.URL, Err: ErrNavigationFailed})
+	case "browsingContext.navigationAborted":
+		events.NavigationFailed(params.Context, navigation, &NavigationError{URL: params.URL, Err: ErrNavigationAborted})
+	case "network.beforeRequestSent":
+		events.Request(params.Context, params.Request.Request, false)
+	case "network.responseCompleted":
+		if navigation != "" && params.Response.Status >= 400 {
+			events.NavigationFailed(params.Context, navigation, &NavigationError{
+				URL:        params.Response.URL,
+				StatusCode: params.Response.Status,
+				Text:       params.Response.StatusText,
+				Err:        ErrHTTPStatus,
+			})
+		}
+		events.Request(params.Context, params.Request.Request, true)
+	case "network.fetchError":
+		if navigation != "" {
+			events.NavigationFailed(params.Context, navigation, newNavigationError(params.Request.URL, params.ErrorText))
+		}
+		events.Request(params.Context, params.Request.Request, true)
+	}
+}
+
+// send sends the command and waits for its result.
+func (p *bidi) send(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	conn := p.conn()
+	id := conn.next()
+	started := time.Now()
+	raw, err := conn.call(ctx, id, h{"id": id, "method": method, "params": params})
+	if params, ok := params.(h); ok && params["context"] != nil {
+		logCommand(p.logger, method, started, err, "target", params["context"])
+	} else {
+		logCommand(p.logger, method, started, err)
+	}
+	return raw, err
+}
+
+func (p *bidi) Close() error {
+	conn := p.conn()
+	return conn.send(h{"id": conn.next(), "method": "browser.close", "params": h{}})
+}
+
+func (p *bidi) Disconnect() error {
+	return p.conn().close()
+}
+
+// Send sends the command as is, BiDi commands carry the browsing context in
+// their params.
+func (p *bidi) Send(ctx context.Context, tab, method string, params interface{}) (json.RawMessage, error) {
+	if params == nil {
+		params = h{}
+	}
+	return p.send(ctx, method, params)
+}
+
+func (p *bidi) Attach(ctx context.Context, tab string) error {
+	// events of all browsing contexts are subscribed to on connect
+	return nil
+}
+
+func (p *bidi) NewTab(ctx context.Context, url string) (string, error) {
+	raw, err := p.send(ctx, "browsingContext.create", h{"type": "tab"})
+	if err != nil {
+		return "", err
+	}
+	res := struct {
+		Context string `json:"context"`
+	}{}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return "", err
+	}
+	if url != "about:blank" {
+		if err := p.Navigate(ctx, res.Context, url); err != nil {
+			return "", err
+		}
+	}
+	return res.Context, nil
+}
+
+func (p *bidi) ActivateTab(ctx context.Context, tab string) error {
+	_, err := p.send(ctx, "browsingContext.activate", h{"context": tab})
+	return err
+}
+
+func (p *bidi) CloseTab(ctx context.Context, tab string) error {
+	_, err := p.send(ctx, "browsingContext.close", h{"context": tab})
+	return err
+}
+
+func (p *bidi) Navigate(ctx context.Context, tab, url string) error {
+	_, err := p.send(ctx, "browsingContext.navigate", h{"context": tab, "url": url, "wait": "none"})
+	return err
+}
+
+func (p *bidi) Evaluate(ctx context.Context, tab, realm, expr string) (Value, error) {
+	target := h{"context": tab}
+	if realm != "" {
+		target = h{"realm": realm}
+	}
+	raw, err := p.send(ctx, "script.evaluate", h{
+		"expression":      expr,
+		"target":          target,
+		"awaitPromise":    true,
+		"resultOwnership": "none",
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := struct {
+		Type             string    `json:"type"`
+		Result           bidiValue `json:"result"`
+		ExceptionDetails struct {
+			Text         string          `json:"text"`
+			LineNumber   int             `json:"lineNumber"`
+			ColumnNumber int             `json:"columnNumber"`
+			Exception    bidiValue       `json:"exception"`
+			StackTrace   *bidiStackTrace `json:"stackTrace"`
+		} `json:"exceptionDetails"`
+	}{}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, err
+	}
+	if res.Type == "exception" {
+		d := res.ExceptionDetails
+		e := &JSException{Text: d.Text, LineNumber: d.LineNumber, ColumnNumber: d.ColumnNumber}
+		if v, ok := d.Exception.toJSON(); ok {
+			e.Value = v
+		}
+		e.setStackTrace(d.StackTrace)
+		return nil, e
+	}
+	return res.Result.toValue(), nil
+}
+
+func (p *bidi) AddScript(ctx context.Context, tab, source string) error {
+	_, err := p.send(ctx, "script.addPreloadScript", h{
+		"functionDeclaration": "() => {\n" + source + "\n}",
+		"contexts":            []string{tab},
+	})
+	return err
+}
+
+// Intercept intercepts all requests of the browsing context, BiDi URL
+// patterns are not globs. Requests not matching the patterns are continued by
+// the caller.
+func (p *bidi) Intercept(ctx context.Context, tab string, patterns []string, navigations bool) error {
+	p.Lock()
+	intercept, ok := p.intercepts[tab]
+	p.Unlock()
+	if ok == (len(patterns) > 0 || navigations) {
+		return nil
+	}
+	if ok {
+		if _, err := p.send(ctx, "network.removeIntercept", h{"intercept": intercept}); err != nil {
+			return err
+		}
+		p.Lock()
+		delete(p.intercepts, tab)
+		p.Unlock()
+		return nil
+	}
+	raw, err := p.send(ctx, "network.addIntercept", h{"phases": []string{"beforeRequestSent"}, "contexts": []string{tab}})
+	if err != nil {
+		return err
+	}
+	res := struct {
+		Intercept string `json:"intercept"`
+	}{}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return err
+	}
+	p.Lock()
+	p.intercepts[tab] = res.Intercept
+	p.Unlock()
+	return nil
+}
+
+func (p *bidi) ContinueRequest(ctx context.Context, tab, request string, headers map[string]string) error {
+	params := h{"request": request}
+	if headers != nil {
+		params["headers"] = bidiHeaders(headers)
+	}
+	_, err := p.send(ctx, "network.continueRequest", params)
+	return err
+}
+
+func (p *bidi) FulfillRequest(ctx context.Context, tab, request string, status int, headers map[string]string, body []byte) error {
+	params := h{
+		"request":    request,
+		"statusCode": status,
+		"body":       h{"type": "base64", "value": base64.StdEncoding.EncodeToString(body)},
+	}
+	if headers != nil {
+		params["headers"] = bidiHeaders(headers)
+	}
+	_, err := p.send(ctx, "network.provideResponse", params)
+	return err
+}
+
+func (p *bidi) FailRequest(ctx context.Context, tab, request string) error {
+	_, err := p.send(ctx, "network.failRequest", h{"request": request})
+	return err
+}
+
+func (p *bidi) Screenshot(ctx context.Context, tab string, format ImageFormat, quality int, fullPage bool, clip *Rect) ([]byte, error) {
+	imageFormat := h{"type": "image/" + string(format)}
+	if format == ImageJPEG && quality > 0 {
+		imageFormat["quality"] = float64(quality) / 100
+	}
+	params := h{"context": tab, "format": imageFormat}
+	if fullPage || clip != nil {
+		params["origin"] = "document"
+	}
+	if clip != nil {
+		params["clip"] = h{"type": "box", "x": clip.X, "y": clip.Y, "width": clip.Width, "height": clip.Height}
+	}
+	raw, err := p.send(ctx, "browsingContext.captureScreenshot", params)
+	if err != nil {
+		return nil, err
+	}
+	res := struct {
+		Data string `json:"data"`
+	}{}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(res.Data)
+}
+
+// cmPerInch converts the PDFOptions inches to the BiDi centimeters.
+const cmPerInch = 2.54
+
+func (p *bidi) PrintToPDF(ctx context.Context, tab string, opts PDFOptions) ([]byte, error) {
+	if opts.HeaderTemplate != "" || opts.FooterTemplate != "" {
+		return nil, fmt.Errorf("header and footer templates are not supported with %s", ProtocolBiDi)
+	}
+	orientation := "portrait"
+	if opts.Landscape {
+		orientation = "landscape"
+	}
+	params := h{"context": tab, "background": opts.Background, "orientation": orientation}
+	if opts.Paper != (PaperSize{}) {
+		params["page"] = h{"width": opts.Paper.Width * cmPerInch, "height": opts.Paper.Height * cmPerInch}
+	}
+	if m := opts.Margins; m != nil {
+		params["margin"] = h{
+			"top":    m.Top * cmPerInch,
+			"bottom": m.Bottom * cmPerInch,
+			"left":   m.Left * cmPerInch,
+			"right":  m.Right * cmPerInch,
+		}
+	}
+	if opts.Scale != 0 {
+		params["scale"] = opts.Scale
+	}
+	if ranges := opts.pageRanges(); len(ranges) > 0 {
+		params["pageRanges"] = ranges
+	}
+	raw, err := p.send(ctx, "browsingContext.print", params)
+	if err != nil {
+		return nil, err
+	}
+	res := struct {
+		Data string `json:"data"`
+	}{}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(res.Data)
+}
+
+// ClearBrowsingData deletes all cookies. BiDi cannot clear the HTTP cache, it
+// is left untouched and reported as ErrNotSupported. The storage of the
+// origins, e.g. localStorage and IndexedDB, is left untouched too.
+func (p *bidi) ClearBrowsingData(ctx context.Context, tab string) error {
+	if _, err := p.send(ctx, "storage.deleteCookies", h{}); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s cannot clear the HTTP cache", ErrNotSupported, ProtocolBiDi)
+}
+
+// bidiStackTrace is a mirror of the script.StackTrace.
+type bidiStackTrace struct {
+	CallFrames []CallFrame `json:"callFrames"`
+}
+
+// setStackTrace sets the stack trace, and the location of the exception from
+// its top frame if it is not known.
+func (e *JSException) setStackTrace(st *bidiStackTrace) {
+	if st == nil || len(st.CallFrames) == 0 {
+		return
+	}
+	e.StackTrace = st.CallFrames
+	if e.URL == "" {
+		top := st.CallFrames[0]
+		e.URL = top.URL
+		if e.LineNumber == 0 && e.ColumnNumber == 0 {
+			e.LineNumber, e.ColumnNumber = top.LineNumber, top.ColumnNumber
+		}
+	}
+}
+
+// bidiValue is a mirror of the script.RemoteValue.
+type bidiValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+func (v bidiValue) toValue() Value {
+	if v.Type == "undefined" {
+		return &value{undefined: true}
+	}
+	if b, ok := v.toJSON(); ok {
+		return &value{raw: b}
+	}
+	// values which can't be serialized (e.g. DOM nodes or functions) are
+	// represented by their type
+	b, _ := json.Marshal(v.Type)
+	return &value{raw: b}
+}
+
+// toJSON returns the JSON encoding of the value, if it can be serialized.
+func (v bidiValue) toJSON() (json.RawMessage, bool) {
+	switch v.Type {
+	case "undefined", "null":
+		return json.RawMessage("null"), true
+	case "string", "boolean", "date":
+		return v.Value, len(v.Value) > 0
+	case "number", "bigint":
+		// NaN, -0, Infinity, -Infinity and bigints are strings, as in CDP
+		return v.Value, len(v.Value) > 0
+	case "regexp":
+		re := struct {
+			Pattern string `json:"pattern"`
+			Flags   string `json:"flags"`
+		}{}
+		if err := json.Unmarshal(v.Value, &re); err != nil {
+			return nil, false
+		}
+		b, _ := json.Marshal("/" + re.Pattern + "/" + re.Flags)
+		return b, true
+	case "array", "set":
+		items := []bidiValue{}
+		if err := json.Unmarshal(v.Value, &items); err != nil {
+			return nil, false
+		}
+		values := []json.RawMessage{}
+		for _, item := range items {
+			b, ok := item.toJSON()
+			if !ok {
+				b = json.RawMessage("null")
+			}
+			values = append(values, b)
+		}
+		b, err := json.Marshal(values)
+		return b, err == nil
+	case "object", "map":
+		entries := [][2]json.RawMessage{}
+		if err := json.Unmarshal(v.Value, &entries); err != nil {
+			return nil, false
+		}
+		object := map[string]json.RawMessage{}
+		for _, entry := range entries {
+			key := ""
+			if err := json.Unmarshal(entry[0], &key); err != nil {
+				// non-string map keys are remote values
+				k := bidiValue{}
+				if err := json.Unmarshal(entry[0], &k); err != nil {
+					continue
+				}
+				b, _ := k.toJSON()
+				key = strings.Trim(string(b), `"`)
+			}
+			item := bidiValue{}
+			if err := json.Unmarshal(entry[1], &item); err != nil {
+				continue
+			}
+			if b, ok := item.toJSON(); ok && item.Type != "undefined" {
+				object[key] = b
+			}
+		}
+		b, err := json.Marshal(object)
+		return b, err == nil
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,75 @@
+package gofirefox
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// profileCache keeps the last known good copy of the downloaded user.js, along
+// with the headers used to revalidate it. A nil cache stores nothing.
+type profileCache struct {
+	dir string
+}
+
+type cacheEntry struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Signature    []byte `json:"signature,omitempty"`
+
+	data []byte
+}
+
+func newProfileCache(dir string) *profileCache {
+	if dir == "" {
+		return nil
+	}
+	return &profileCache{dir: dir}
+}
+
+// path returns the path of the cached file for the url, without extension.
+func (c *profileCache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:16]))
+}
+
+// load returns the cached entry for the url, or nil if there is none.
+func (c *profileCache) load(url string) *cacheEntry {
+	if c == nil {
+		return nil
+	}
+	path := c.path(url)
+	meta, err := os.ReadFile(path + ".json")
+	if err != nil {
+		return nil
+	}
+	e := &cacheEntry{}
+	if err := json.Unmarshal(meta, e); err != nil || e.URL != url {
+		return nil
+	}
+	if e.data, err = os.ReadFile(path + ".js"); err != nil {
+		return nil
+	}
+	return e
+}
+
+func (c *profileCache) store(e *cacheEntry) error {
+	if c == nil {
+		return nil
+	}
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return err
+	}
+	meta, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	path := c.path(e.URL)
+	if err := writeFileAtomic(path+".js", e.data); err != nil {
+		return err
+	}
+	return writeFileAtomic(path+".json", meta)
+}
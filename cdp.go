@@ -0,0 +1,628 @@
+package gofirefox
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/unikiosk/go-firefox/prefs"
+	"github.com/unikiosk/go-firefox/protocol"
+)
+
+// cdp drives firefox over the Chrome DevTools Protocol. Each tab is attached
+// to with its own session, and the session commands are relayed with
+// Target.sendMessageToTarget.
+type cdp struct {
+	logger *slog.Logger
+
+	sync.Mutex
+	current  *conn             // connection to the running firefox
+	sessions map[string]string // target IDs by session ID
+	targets  map[string]string // session IDs by target ID
+	relays   map[int]int       // Target.sendMessageToTarget ID to the relayed command ID
+
+	frames    map[string]protocol.PageFrameID           // main frame IDs by target ID
+	documents map[protocol.NetworkRequestID]cdpDocument // document requests of the main frames
+}
+
+// cdpDocument is the request of the document loaded by a navigation.
+type cdpDocument struct {
+	target string
+	loader protocol.NetworkLoaderID
+	url    string
+}
+
+func newCDP(logger *slog.Logger) *cdp {
+	return &cdp{
+		current:  newConn(2),
+		logger:   logger,
+		sessions: map[string]string{},
+		targets:  map[string]string{},
+		relays:   map[int]int{},
+
+		frames:    map[string]protocol.PageFrameID{},
+		documents: map[protocol.NetworkRequestID]cdpDocument{},
+	}
+}
+
+// Msg is a struct for incoming messages (results and async events)
+type msg struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  json.RawMessage `json:"error"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// targetMessage is the message received from the session.
+type targetMessage struct {
+	ID     int             `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Error  json.RawMessage `json:"error"`
+	Result json.RawMessage `json:"result"`
+}
+
+func (p *cdp) Prefs() []prefs.Pref {
+	// CDP is disabled by default since firefox 129
+	return []prefs.Pref{{Name: "remote.active-protocols", Value: 2}}
+}
+
+func (p *cdp) Endpoint() *regexp.Regexp {
+	return regexp.MustCompile(`^DevTools listening on (ws://.*?)\r?\n$`)
+}
+
+func (p *cdp) conn() *conn {
+	p.Lock()
+	defer p.Unlock()
+	return p.current
+}
+
+func (p *cdp) Connect(ctx context.Context, url string, events ProtocolEvents) (string, error) {
+	// The first two IDs are used internally during the initialization
+	conn := newConn(2)
+	p.Lock()
+	p.current = conn
+	p.sessions = map[string]string{}
+	p.targets = map[string]string{}
+	p.relays = map[int]int{}
+	p.frames = map[string]protocol.PageFrameID{}
+	p.documents = map[protocol.NetworkRequestID]cdpDocument{}
+	p.Unlock()
+
+	if err := conn.dial(url); err != nil {
+		return "", err
+	}
+	target, err := p.findTarget(conn)
+	if err != nil {
+		return "", err
+	}
+	go p.readLoop(conn, events)
+	return target, nil
+}
+
+// findTarget enables the target discovery and waits for the first page.
+func (p *cdp) findTarget(conn *conn) (string, error) {
+	discover := protocol.TargetSetDiscoverTargetsParams{Discover: true}
+	err := conn.send(h{"id": 0, "method": discover.Method(), "params": discover})
+	if err != nil {
+		return "", err
+	}
+	for {
+		m := msg{}
+		if err = conn.receive(&m); err != nil {
+			return "", err
+		} else if m.Method == (protocol.TargetCreatedEvent{}).Method() {
+			created := protocol.TargetCreatedEvent{}
+			if err := json.Unmarshal(m.Params, &created); err != nil {
+				return "", err
+			} else if created.TargetInfo.Type == "page" {
+				return string(created.TargetInfo.TargetID), nil
+			}
+		}
+	}
+}
+
+func (p *cdp) readLoop(conn *conn, events ProtocolEvents) {
+	defer events.Disconnected()
+	defer conn.close()
+	for {
+		m := msg{}
+		if err := conn.receive(&m); err != nil {
+			return
+		}
+
+		if m.ID != 0 {
+			p.complete(conn, m.ID, m.Result, m.Error)
+		} else if m.Method == (protocol.TargetReceivedMessageFromTargetEvent{}).Method() {
+			params := protocol.TargetReceivedMessageFromTargetEvent{}
+			err := json.Unmarshal(m.Params, &params)
+			if err != nil {
+				p.logger.Warn("invalid target message", "err", err)
+			}
+			p.Lock()
+			target, ok := p.sessions[string(params.SessionID)]
+			p.Unlock()
+			if !ok {
+				continue
+			}
+
+			res := targetMessage{}
+			err = json.Unmarshal([]byte(params.Message), &res)
+			if err != nil {
+				p.logger.Warn("invalid target message", "target", target, "session", params.SessionID, "err", err)
+			}
+
+			if res.ID != 0 {
+				p.complete(conn, res.ID, res.Result, res.Error)
+			} else {
+				p.handleEvent(events, target, &res)
+			}
+		} else {
+			events.Event("", m.Method, m.Params)
+			switch m.Method {
+			case protocol.TargetCreatedEvent{}.Method(), protocol.TargetInfoChangedEvent{}.Method():
+				// both events carry the target info only
+				info := protocol.TargetInfoChangedEvent{}
+				json.Unmarshal(m.Params, &info)
+				if info.TargetInfo.Type != "page" {
+					break
+				}
+				if m.Method == (protocol.TargetCreatedEvent{}).Method() {
+					events.TabOpened(string(info.TargetInfo.TargetID), string(info.TargetInfo.OpenerID), info.TargetInfo.URL)
+				}
+				events.TabChanged(string(info.TargetInfo.TargetID), info.TargetInfo.URL)
+			case protocol.TargetDestroyedEvent{}.Method():
+				params := protocol.TargetDestroyedEvent{}
+				json.Unmarshal(m.Params, &params)
+				target := string(params.TargetID)
+				p.Lock()
+				delete(p.sessions, p.targets[target])
+				delete(p.targets, target)
+				delete(p.frames, target)
+				p.Unlock()
+				events.TabClosed(target)
+			}
+		}
+	}
+}
+
+// handleEvent handles the event from the session attached to the target.
+func (p *cdp) handleEvent(events ProtocolEvents, target string, res *targetMessage) {
+	events.Event(target, res.Method, res.Params)
+	switch res.Method {
+	case protocol.RuntimeExecutionContextCreatedEvent{}.Method():
+		params := protocol.RuntimeExecutionContextCreatedEvent{}
+		if err := json.Unmarshal(res.Params, &params); err == nil {
+			events.RealmCreated(target, strconv.Itoa(int(params.Context.ID)))
+		}
+	case protocol.RuntimeConsoleAPICalledEvent{}.Method():
+		params := protocol.RuntimeConsoleAPICalledEvent{}
+		if err := json.Unmarshal(res.Params, &params); err != nil {
+			return
+		}
+		msg := ConsoleMessage{Type: params.Type, Timestamp: epochMillis(float64(params.Timestamp))}
+		for _, arg := range params.Args {
+			msg.Args = append(msg.Args, newValue(arg))
+		}
+		msg.Text = consoleText(msg.Args)
+		msg.setCallFrames(callFrames(params.StackTrace))
+		events.Console(target, strconv.Itoa(int(params.ExecutionContextID)), msg)
+	case protocol.RuntimeExceptionThrownEvent{}.Method():
+		params := protocol.RuntimeExceptionThrownEvent{}
+		if err := json.Unmarshal(res.Params, &params); err == nil {
+			events.Exception(target, newException(&params.ExceptionDetails))
+		}
+	case protocol.FetchRequestPausedEvent{}.Method():
+		params := protocol.FetchRequestPausedEvent{}
+		if err := json.Unmarshal(res.Params, &params); err != nil {
+			p.logger.Warn("invalid paused request", "target", target, "err", err)
+			return
+		}
+		headers := map[string]string{}
+		for name, value := range params.Request.Headers {
+			headers[name] = fmt.Sprint(value)
+		}
+		p.Lock()
+		main, known := p.frames[target]
+		p.Unlock()
+		events.RequestPaused(target, &InterceptedRequest{
+			URL:          params.Request.URL + params.Request.URLFragment,
+			Method:       params.Request.Method,
+			Headers:      headers,
+			PostData:     params.Request.PostData,
+			ResourceType: ResourceType(params.ResourceType),
+			id:           string(params.RequestID),
+			navigation:   params.ResourceType == protocol.NetworkResourceTypeDocument && (!known || params.FrameID == main),
+		})
+	default:
+		p.handleNavigationEvent(events, target, res)
+	}
+}
+
+// handleNavigationEvent reports the navigations of the main frame of the
+// target, and the requests of the target.
+func (p *cdp) handleNavigationEvent(events ProtocolEvents, target string, res *targetMessage) {
+	switch res.Method {
+	case protocol.PageFrameNavigatedEvent{}.Method():
+		params := protocol.PageFrameNavigatedEvent{}
+		if err := json.Unmarshal(res.Params, &params); err == nil && params.Frame.ParentID == "" {
+			p.Lock()
+			p.frames[target] = params.Frame.ID
+			p.Unlock()
+			events.NavigationStarted(target, string(params.Frame.LoaderID), params.Frame.URL)
+		}
+	case protocol.PageDOMContentEventFiredEvent{}.Method():
+		events.LoadStateReached(target, LoadStateDOMContentLoaded)
+	case protocol.PageLoadEventFiredEvent{}.Method():
+		events.LoadStateReached(target, LoadStateLoad)
+	case protocol.NetworkRequestWillBeSentEvent{}.Method():
+		params := protocol.NetworkRequestWillBeSentEvent{}
+		if err := json.Unmarshal(res.Params, &params); err != nil {
+			return
+		}
+		p.Lock()
+		main, known := p.frames[target]
+		document := params.Type == protocol.NetworkResourceTypeDocument && (!known || params.FrameID == main)
+		if document {
+			// redirects keep the request ID
+			p.documents[params.RequestID] = cdpDocument{target: target, loader: params.LoaderID, url: params.Request.URL}
+		}
+		p.Unlock()
+		if document {
+			events.NavigationStarted(target, string(params.LoaderID), params.Request.URL)
+		}
+		events.Request(target, string(params.RequestID), false)
+	case protocol.NetworkResponseReceivedEvent{}.Method():
+		params := protocol.NetworkResponseReceivedEvent{}
+		if err := json.Unmarshal(res.Params, &params); err != nil {
+			return
+		}
+		p.Lock()
+		doc, ok := p.documents[params.RequestID]
+		p.Unlock()
+		if ok && params.Response.Status >= 400 {
+			events.NavigationFailed(target, string(doc.loader), &NavigationError{
+				URL:        params.Response.URL,
+				StatusCode: params.Response.Status,
+				Text:       params.Response.StatusText,
+				Err:        ErrHTTPStatus,
+			})
+		}
+	case protocol.NetworkLoadingFinishedEvent{}.Method():
+		params := protocol.NetworkLoadingFinishedEvent{}
+		if err := json.Unmarshal(res.Params, &params); err != nil {
+			return
+		}
+		p.Lock()
+		delete(p.documents, params.RequestID)
+		p.Unlock()
+		events.Request(target, string(params.RequestID), true)
+	case protocol.NetworkLoadingFailedEvent{}.Method():
+		params := protocol.NetworkLoadingFailedEvent{}
+		if err := json.Unmarshal(res.Params, &params); err != nil {
+			return
+		}
+		p.Lock()
+		doc, ok := p.documents[params.RequestID]
+		delete(p.documents, params.RequestID)
+		p.Unlock()
+		if ok {
+			err := newNavigationError(doc.url, params.ErrorText)
+			if params.Canceled {
+				err.Err = ErrNavigationAborted
+			}
+			events.NavigationFailed(target, string(doc.loader), err)
+		}
+		events.Request(target, string(params.RequestID), true)
+	}
+}
+
+// complete passes the result of the command to its caller. Errors of
+// Target.sendMessageToTarget are passed to the caller of the relayed command.
+func (p *cdp) complete(conn *conn, id int, value json.RawMessage, errRaw json.RawMessage) {
+	p.Lock()
+	inner, relayed := p.relays[id]
+	delete(p.relays, id)
+	p.Unlock()
+	err := protocolError(errRaw)
+	if relayed {
+		if err != nil {
+			conn.complete(inner, nil, err)
+		}
+		// otherwise the relayed command result arrives separately
+		return
+	}
+	conn.complete(id, value, err)
+}
+
+// protocolError returns the *ProtocolError of the command result, or nil if
+// there is none.
+func protocolError(raw json.RawMessage) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	e := struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    string `json:"data"`
+	}{}
+	if err := json.Unmarshal(raw, &e); err != nil || e.Message == "" {
+		return &ProtocolError{Message: string(raw)}
+	}
+	return &ProtocolError{Code: e.Code, Message: e.Message, Data: e.Data}
+}
+
+// sendBrowser sends the command to the browser and waits for its result.
+func (p *cdp) sendBrowser(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	conn := p.conn()
+	id := conn.next()
+	started := time.Now()
+	raw, err := conn.call(ctx, id, h{"id": id, "method": method, "params": params})
+	logCommand(p.logger, method, started, err)
+	return raw, err
+}
+
+// sendTo sends the command to the session attached to the target and waits
+// for its result.
+func (p *cdp) sendTo(ctx context.Context, target string, method string, params interface{}) (json.RawMessage, error) {
+	p.Lock()
+	session, ok := p.targets[target]
+	p.Unlock()
+	if !ok {
+		return nil, ErrTabClosed
+	}
+
+	conn := p.conn()
+	id := conn.next()
+	b, err := json.Marshal(h{"id": id, "method": method, "params": params})
+	if err != nil {
+		return nil, err
+	}
+	relay := conn.next()
+	p.Lock()
+	p.relays[relay] = id
+	p.Unlock()
+	started := time.Now()
+	send := protocol.TargetSendMessageToTargetParams{Message: string(b), SessionID: protocol.TargetSessionID(session)}
+	raw, err := conn.call(ctx, id, h{"id": relay, "method": send.Method(), "params": send})
+	logCommand(p.logger, method, started, err, "target", target, "session", session)
+	return raw, err
+}
+
+// call sends the command to the target, or to the browser if target is empty,
+// and decodes its result into res unless it is nil.
+func (p *cdp) call(ctx context.Context, target string, cmd protocol.Command, res interface{}) error {
+	raw, err := p.Send(ctx, target, cmd.Method(), cmd)
+	if err != nil || res == nil {
+		return err
+	}
+	return json.Unmarshal(raw, res)
+}
+
+func (p *cdp) Close() error {
+	conn := p.conn()
+	cmd := protocol.BrowserCloseParams{}
+	return conn.send(h{"id": conn.next(), "method": cmd.Method(), "params": cmd})
+}
+
+func (p *cdp) Disconnect() error {
+	return p.conn().close()
+}
+
+func (p *cdp) Send(ctx context.Context, target, method string, params interface{}) (json.RawMessage, error) {
+	if target == "" {
+		return p.sendBrowser(ctx, method, params)
+	}
+	return p.sendTo(ctx, target, method, params)
+}
+
+func (p *cdp) Attach(ctx context.Context, target string) error {
+	res := protocol.TargetAttachToTargetReturns{}
+	err := p.call(ctx, "", protocol.TargetAttachToTargetParams{TargetID: protocol.TargetID(target)}, &res)
+	if err != nil {
+		return err
+	}
+	session := string(res.SessionID)
+	p.Lock()
+	p.sessions[session] = target
+	p.targets[target] = session
+	p.Unlock()
+	p.logger.Debug("attached to target", "target", target, "session", session)
+
+	for _, cmd := range []protocol.Command{
+		protocol.PageEnableParams{},
+		protocol.TargetSetAutoAttachParams{AutoAttach: true, WaitForDebuggerOnStart: false},
+		protocol.NetworkEnableParams{},
+		protocol.RuntimeEnableParams{},
+		protocol.SecurityEnableParams{},
+		protocol.PerformanceEnableParams{},
+		protocol.LogEnableParams{},
+	} {
+		if err := p.call(ctx, target, cmd, nil); err != nil {
+			return err
+		}
+	}
+
+	// the main frame tells the navigations of the tab from those of its
+	// frames
+	tree := protocol.PageGetFrameTreeReturns{}
+	if err := p.call(ctx, target, protocol.PageGetFrameTreeParams{}, &tree); err != nil {
+		return err
+	}
+	if id := tree.FrameTree.Frame.ID; id != "" {
+		p.Lock()
+		p.frames[target] = id
+		p.Unlock()
+	}
+	return nil
+}
+
+func (p *cdp) NewTab(ctx context.Context, url string) (string, error) {
+	res := protocol.TargetCreateTargetReturns{}
+	if err := p.call(ctx, "", protocol.TargetCreateTargetParams{URL: url}, &res); err != nil {
+		return "", err
+	}
+	return string(res.TargetID), nil
+}
+
+func (p *cdp) ActivateTab(ctx context.Context, target string) error {
+	return p.call(ctx, "", protocol.TargetActivateTargetParams{TargetID: protocol.TargetID(target)}, nil)
+}
+
+func (p *cdp) CloseTab(ctx context.Context, target string) error {
+	return p.call(ctx, "", protocol.TargetCloseTargetParams{TargetID: protocol.TargetID(target)}, nil)
+}
+
+func (p *cdp) Navigate(ctx context.Context, target, url string) error {
+	res := protocol.PageNavigateReturns{}
+	if err := p.call(ctx, target, protocol.PageNavigateParams{URL: url}, &res); err != nil {
+		return err
+	}
+	if res.ErrorText != "" {
+		return newNavigationError(url, res.ErrorText)
+	}
+	return nil
+}
+
+func (p *cdp) Evaluate(ctx context.Context, target, realm, expr string) (Value, error) {
+	params := protocol.RuntimeEvaluateParams{Expression: expr, AwaitPromise: true, ReturnByValue: true}
+	if realm != "" {
+		id, err := strconv.Atoi(realm)
+		if err != nil {
+			return nil, err
+		}
+		params.ContextID = protocol.RuntimeExecutionContextID(id)
+	}
+	res := protocol.RuntimeEvaluateReturns{}
+	if err := p.call(ctx, target, params, &res); err != nil {
+		return nil, err
+	}
+	if res.ExceptionDetails != nil {
+		return nil, newException(res.ExceptionDetails)
+	}
+	return newValue(res.Result), nil
+}
+
+func (p *cdp) AddScript(ctx context.Context, target, source string) error {
+	return p.call(ctx, target, protocol.PageAddScriptToEvaluateOnNewDocumentParams{Source: source}, nil)
+}
+
+func (p *cdp) Intercept(ctx context.Context, target string, patterns []string, navigations bool) error {
+	if len(patterns) == 0 && !navigations {
+		return p.call(ctx, target, protocol.FetchDisableParams{}, nil)
+	}
+	enable := protocol.FetchEnableParams{}
+	for _, pattern := range patterns {
+		enable.Patterns = append(enable.Patterns, protocol.FetchRequestPattern{URLPattern: pattern})
+	}
+	if navigations {
+		enable.Patterns = append(enable.Patterns, protocol.FetchRequestPattern{URLPattern: "*", ResourceType: protocol.NetworkResourceTypeDocument})
+	}
+	return p.call(ctx, target, enable, nil)
+}
+
+func (p *cdp) ContinueRequest(ctx context.Context, target, request string, headers map[string]string) error {
+	return p.call(ctx, target, protocol.FetchContinueRequestParams{
+		RequestID: protocol.FetchRequestID(request),
+		Headers:   headerEntries(headers),
+	}, nil)
+}
+
+func (p *cdp) FulfillRequest(ctx context.Context, target, request string, status int, headers map[string]string, body []byte) error {
+	return p.call(ctx, target, protocol.FetchFulfillRequestParams{
+		RequestID:       protocol.FetchRequestID(request),
+		ResponseCode:    status,
+		ResponseHeaders: headerEntries(headers),
+		Body:            base64.StdEncoding.EncodeToString(body),
+	}, nil)
+}
+
+func (p *cdp) FailRequest(ctx context.Context, target, request string) error {
+	return p.call(ctx, target, protocol.FetchFailRequestParams{
+		RequestID:   protocol.FetchRequestID(request),
+		ErrorReason: protocol.NetworkErrorReasonFailed,
+	}, nil)
+}
+
+func (p *cdp) Screenshot(ctx context.Context, target string, format ImageFormat, quality int, fullPage bool, clip *Rect) ([]byte, error) {
+	params := protocol.PageCaptureScreenshotParams{Format: string(format)}
+	if format == ImageJPEG {
+		params.Quality = quality
+	}
+	if fullPage && clip == nil {
+		metrics := protocol.PageGetLayoutMetricsReturns{}
+		if err := p.call(ctx, target, protocol.PageGetLayoutMetricsParams{}, &metrics); err != nil {
+			return nil, err
+		}
+		clip = &Rect{Width: metrics.ContentSize.Width, Height: metrics.ContentSize.Height}
+	}
+	if clip != nil {
+		params.Clip = &protocol.PageViewport{X: clip.X, Y: clip.Y, Width: clip.Width, Height: clip.Height, Scale: 1}
+		params.CaptureBeyondViewport = true
+	}
+	res := protocol.PageCaptureScreenshotReturns{}
+	if err := p.call(ctx, target, params, &res); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(res.Data)
+}
+
+func (p *cdp) PrintToPDF(ctx context.Context, target string, opts PDFOptions) ([]byte, error) {
+	params := protocol.PagePrintToPDFParams{
+		Landscape:       opts.Landscape,
+		PrintBackground: opts.Background,
+		PaperWidth:      opts.Paper.Width,
+		PaperHeight:     opts.Paper.Height,
+		Scale:           opts.Scale,
+		PageRanges:      opts.PageRanges,
+	}
+	if m := opts.Margins; m != nil {
+		params.MarginTop, params.MarginBottom = &m.Top, &m.Bottom
+		params.MarginLeft, params.MarginRight = &m.Left, &m.Right
+	}
+	if opts.HeaderTemplate != "" || opts.FooterTemplate != "" {
+		// both are sent, a missing template is the default one otherwise
+		params.DisplayHeaderFooter = true
+		params.HeaderTemplate, params.FooterTemplate = &opts.HeaderTemplate, &opts.FooterTemplate
+	}
+	res := protocol.PagePrintToPDFReturns{}
+	if err := p.call(ctx, target, params, &res); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(res.Data)
+}
+
+func (p *cdp) ClearBrowsingData(ctx context.Context, target string) error {
+	errs := []error{}
+	for _, cmd := range []protocol.Command{
+		protocol.NetworkClearBrowserCookiesParams{},
+		protocol.NetworkClearBrowserCacheParams{},
+	} {
+		if err := p.call(ctx, target, cmd, nil); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", cmd.Method(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// headerEntries returns the headers sorted by name, or nil if headers is nil.
+func headerEntries(headers map[string]string) []protocol.FetchHeaderEntry {
+	if headers == nil {
+		return nil
+	}
+	entries := []protocol.FetchHeaderEntry{}
+	for name, value := range headers {
+		entries = append(entries, protocol.FetchHeaderEntry{Name: name, Value: value})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries
+}
@@ -1,7 +1,16 @@
 package gofirefox
 
 import (
+	"fmt"
+	"io/fs"
+	"log"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/unikiosk/go-firefox/prefs"
 )
 
 type Config struct {
@@ -9,8 +18,73 @@ type Config struct {
 	ProfileDir string
 	// FirefoxBin is override location for firefox binary
 	FirefoxBin string
-	// ProfileLocationURL is profile location file
+	// ProfileLocationURL is profile location file: http(s):// or file:// URL,
+	// or a local path
 	ProfileLocationURL string
+	// ProfileSource provides user.js, it overrides ProfileLocationURL if set
+	ProfileSource ProfileSource
+	// ProfileSHA256 is the hex encoded SHA-256 checksum the downloaded user.js
+	// must match, if set
+	ProfileSHA256 string
+	// ProfilePublicKey is the minisign public key the downloaded user.js must
+	// be signed with, if set. The signature is loaded from ProfileLocationURL
+	// with the .minisig suffix.
+	ProfilePublicKey string
+	// CacheDir is where the last known good user.js is kept. It is revalidated
+	// on each start and used if the download or verification fails. Empty
+	// disables the cache.
+	CacheDir string
+	// ProfileTemplate is copied into the profile directory before user.js is
+	// downloaded. Its user.js is used if the download fails or
+	// ProfileLocationURL is empty, and the download is given up after 5s
+	// then rather than retried, so offline kiosks start right away.
+	ProfileTemplate fs.FS
+	// Args are extra arguments passed to firefox executable
+	Args []string
+	// Prefs are user preferences merged into the profile user.js. Values must
+	// be bool, string or integer.
+	Prefs map[string]interface{}
+	// Kiosk starts firefox in kiosk mode
+	Kiosk bool
+	// Headless starts firefox without a window, e.g. to print PDFs or take
+	// screenshots on a server
+	Headless bool
+	// WindowWidth and WindowHeight set the initial window size, if not zero
+	WindowWidth  int
+	WindowHeight int
+	// Logger receives the library logs as text lines if Slog is not set
+	Logger *log.Logger
+	// Slog is used for the library logs, it takes precedence over Logger.
+	// slog.Default() is used if neither is set. The
+	// browser console messages and JS exceptions are logged with the "source"
+	// attribute set to "console" and "exception".
+	Slog *slog.Logger
+	// Launcher starts firefox, an ExecLauncher starting FirefoxBin by default
+	Launcher Launcher
+	// Restart makes firefox restarted when it ends unexpectedly, if set
+	Restart *RestartPolicy
+	// Protocol is the remote protocol firefox is driven with, ProtocolCDP by
+	// default
+	Protocol ProtocolName
+	// Navigation restricts where the tabs may navigate, if set
+	Navigation *NavigationPolicy
+	// IdleTimeout resets the session once the user was inactive for this
+	// long, if not zero. See WithIdleReset.
+	IdleTimeout time.Duration
+	// IdleHomeURL is the URL the idle session is reset to, the URL the UI was
+	// created with by default
+	IdleHomeURL string
+	// EventBuffer is the number of events buffered for the UI.Console and
+	// UI.Exceptions channels and for each UI.On handler, 256 by default
+	EventBuffer int
+
+	// userPrefs are raw user_pref(...) lines passed to New
+	userPrefs []string
+	// logger is Slog, Logger or slog.Default(), set by prepare
+	logger *slog.Logger
+	// remoteURL is the websocket URL of the existing firefox passed to
+	// ConnectExisting
+	remoteURL string
 }
 
 const (
@@ -18,36 +92,114 @@ const (
 	DefaultProfileLocation = "https://raw.githubusercontent.com/unikiosk/user.js/master/user.js"
 )
 
-func getConfig() (*Config, error) {
-	c := Config{}
+// defaultConfig returns the configuration with defaults taken from the
+// GOFIREFOX_* environment variables.
+func defaultConfig() *Config {
+	c := Config{
+		ProfileTemplate: DefaultProfileTemplate,
+		Kiosk:           true,
+	}
 
 	if path, ok := os.LookupEnv("GOFIREFOX_BIN"); ok {
 		if _, err := os.Stat(path); err == nil {
 			c.FirefoxBin = path
 		}
-	} else {
+	}
+	if c.FirefoxBin == "" {
 		c.FirefoxBin = FirefoxExecutable()
 	}
 
 	if profileDir, ok := os.LookupEnv("GOFIREFOX_PROFILE_DIR"); ok {
-		err := os.MkdirAll(profileDir, os.ModeDir)
+		c.ProfileDir = profileDir
+	}
+
+	if profileLocation, ok := os.LookupEnv("GOFIREFOX_PROFILE_LOCATION"); ok {
+		c.ProfileLocationURL = profileLocation
+	} else {
+		c.ProfileLocationURL = DefaultProfileLocation
+	}
+
+	c.ProfileSHA256 = os.Getenv("GOFIREFOX_PROFILE_SHA256")
+	c.ProfilePublicKey = os.Getenv("GOFIREFOX_PROFILE_PUBLIC_KEY")
+
+	if cacheDir, ok := os.LookupEnv("GOFIREFOX_CACHE_DIR"); ok {
+		c.CacheDir = cacheDir
+	} else if cacheDir, err := os.UserCacheDir(); err == nil {
+		c.CacheDir = filepath.Join(cacheDir, "gofirefox")
+	}
+
+	if protocol, ok := os.LookupEnv("GOFIREFOX_PROTOCOL"); ok {
+		c.Protocol = ProtocolName(protocol)
+	}
+
+	return &c
+}
+
+// prepare validates the configuration and creates the profile directory.
+func (c *Config) prepare() error {
+	switch {
+	case c.Slog != nil:
+		c.logger = c.Slog
+	case c.Logger != nil:
+		c.logger = textLogger(c.Logger)
+	default:
+		c.logger = slog.Default()
+	}
+	if c.remoteURL != "" {
+		// nothing is started
+		return nil
+	}
+	if c.FirefoxBin == "" && c.Launcher == nil {
+		return fmt.Errorf("firefox executable not found")
+	}
+
+	if c.ProfileDir != "" {
+		err := os.MkdirAll(c.ProfileDir, 0700)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		c.ProfileDir = profileDir
 	} else {
 		tempDir, err := os.MkdirTemp(os.TempDir(), "gofirefox")
 		if err != nil {
-			return nil, err
+			return err
 		}
 		c.ProfileDir = tempDir
 	}
+	return nil
+}
 
-	if profileLocation, ok := os.LookupEnv("GOFIREFOX_PROFILE_LOCATION"); ok {
-		c.ProfileLocationURL = profileLocation
-	} else {
-		c.ProfileLocationURL = DefaultProfileLocation
+// userPreferences returns all configured preferences, Prefs override the raw
+// user_pref lines.
+func (c *Config) userPreferences() ([]prefs.Pref, error) {
+	f, err := prefs.Parse([]byte(strings.Join(c.userPrefs, "\n")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse user preferences: %w", err)
 	}
+	p, err := prefs.FromMap(c.Prefs)
+	if err != nil {
+		return nil, err
+	}
+	return append(f.Prefs(), p...), nil
+}
+
+// textLogger returns the slog logger writing the records as text lines to the
+// log.Logger, which adds its own prefix and timestamp.
+func textLogger(l *log.Logger) *slog.Logger {
+	return slog.New(slog.NewTextHandler(logWriter{l}, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+// logWriter writes each record to the log.Logger.
+type logWriter struct {
+	l *log.Logger
+}
 
-	return &c, nil
+func (w logWriter) Write(p []byte) (int, error) {
+	return len(p), w.l.Output(2, string(p))
 }
@@ -0,0 +1,165 @@
+package gofirefox
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultEventBuffer is the number of events buffered for the Console and
+// Exceptions channels by default.
+const defaultEventBuffer = 256
+
+// eventStream passes the browser events to the channel returned to the user.
+// Events are only buffered once the channel was requested, and dropped when
+// the buffer is full, so a slow reader never stalls the connection.
+type eventStream[T any] struct {
+	size    int
+	dropped atomic.Uint64
+
+	sync.Mutex
+	c chan T
+}
+
+func newEventStream[T any](size int) *eventStream[T] {
+	if size <= 0 {
+		size = defaultEventBuffer
+	}
+	return &eventStream[T]{size: size}
+}
+
+// channel returns the channel of the events, which is never closed.
+func (s *eventStream[T]) channel() <-chan T {
+	s.Lock()
+	defer s.Unlock()
+	if s.c == nil {
+		s.c = make(chan T, s.size)
+	}
+	return s.c
+}
+
+// publish passes the event to the channel, or drops it if the buffer is full.
+func (s *eventStream[T]) publish(event T) {
+	s.Lock()
+	c := s.c
+	s.Unlock()
+	if c == nil {
+		return
+	}
+	select {
+	case c <- event:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// subscription passes the events to its handler from its own goroutine, in
+// the order they were sent.
+type subscription struct {
+	handler func(json.RawMessage)
+	events  chan json.RawMessage
+	done    chan struct{}
+	once    sync.Once
+}
+
+// subscriptions dispatches the protocol events to the subscribed handlers.
+// Events are buffered per subscription and dropped when the buffer is full,
+// so slow handlers never stall the connection.
+type subscriptions struct {
+	size   int
+	logger *slog.Logger
+
+	sync.Mutex
+	byMethod map[string][]*subscription
+	closed   bool
+}
+
+func newSubscriptions(size int, logger *slog.Logger) *subscriptions {
+	if size <= 0 {
+		size = defaultEventBuffer
+	}
+	return &subscriptions{size: size, logger: logger, byMethod: map[string][]*subscription{}}
+}
+
+// subscribe calls handler with the params of the method events of all tabs,
+// until the returned function or close is called.
+func (s *subscriptions) subscribe(method string, handler func(json.RawMessage)) (unsubscribe func()) {
+	sub := &subscription{
+		handler: handler,
+		events:  make(chan json.RawMessage, s.size),
+		done:    make(chan struct{}),
+	}
+	s.Lock()
+	if s.closed {
+		s.Unlock()
+		return func() {}
+	}
+	s.byMethod[method] = append(s.byMethod[method], sub)
+	s.Unlock()
+
+	go func() {
+		for {
+			select {
+			case params := <-sub.events:
+				select {
+				case <-sub.done:
+					return
+				default:
+					sub.handler(params)
+				}
+			case <-sub.done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		s.Lock()
+		subs := s.byMethod[method]
+		for i, other := range subs {
+			if other == sub {
+				// copied, publish may be iterating over subs
+				s.byMethod[method] = append(subs[:i:i], subs[i+1:]...)
+				break
+			}
+		}
+		if len(s.byMethod[method]) == 0 {
+			delete(s.byMethod, method)
+		}
+		s.Unlock()
+		sub.stop()
+	}
+}
+
+// stop stops the goroutine of the subscription.
+func (sub *subscription) stop() {
+	sub.once.Do(func() { close(sub.done) })
+}
+
+// close stops all subscriptions, further subscriptions are no-ops.
+func (s *subscriptions) close() {
+	s.Lock()
+	byMethod := s.byMethod
+	s.byMethod, s.closed = map[string][]*subscription{}, true
+	s.Unlock()
+	for _, subs := range byMethod {
+		for _, sub := range subs {
+			sub.stop()
+		}
+	}
+}
+
+// publish passes the event sent from the tab to the subscribed handlers.
+func (s *subscriptions) publish(tab, method string, params json.RawMessage) {
+	s.Lock()
+	subs := s.byMethod[method]
+	s.Unlock()
+	for _, sub := range subs {
+		select {
+		case sub.events <- params:
+		default:
+			s.logger.Warn("event dropped, the handler is too slow", "method", method, "target", tab)
+		}
+	}
+}
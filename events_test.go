@@ -0,0 +1,41 @@
+package gofirefox
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestSubscriptionsClose(t *testing.T) {
+	s := newSubscriptions(1, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	events := make(chan json.RawMessage, 10)
+	handler := func(params json.RawMessage) { events <- params }
+	s.subscribe("Page.loadEventFired", handler)
+	sub := s.byMethod["Page.loadEventFired"][0]
+
+	s.publish("page-1", "Page.loadEventFired", json.RawMessage(`{}`))
+	select {
+	case <-events:
+	case <-time.After(time.Second):
+		t.Fatal("handler was not called")
+	}
+
+	s.close()
+	select {
+	case <-sub.done:
+	default:
+		t.Error("subscription was not stopped")
+	}
+	s.publish("page-1", "Page.loadEventFired", json.RawMessage(`{}`))
+	s.subscribe("Page.loadEventFired", handler)()
+	if len(s.byMethod) != 0 {
+		t.Errorf("subscribed after close: %v", s.byMethod)
+	}
+	select {
+	case <-events:
+		t.Error("handler called after close")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
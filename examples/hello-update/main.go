@@ -3,7 +3,6 @@ package main
 import (
 	"context"
 	"log"
-	"time"
 
 	gofirefox "github.com/unikiosk/go-firefox"
 )
@@ -14,7 +13,7 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer ui.Stop()
+	defer ui.Close()
 
 	go func() {
 		err := ui.Run(ctx)
@@ -22,14 +21,12 @@ func main() {
 			log.Fatal(err)
 		}
 	}()
-	// Wait until UI window is closed
-
-	time.Sleep(time.Second * 10)
-
-	err = ui.Load("https://synpse.net/blog/")
+	// LoadAndWait waits until firefox has started
+	err = ui.LoadAndWait(ctx, "https://synpse.net/blog/", gofirefox.LoadStateLoad)
 	if err != nil {
 		log.Fatal(err)
 	}
+	log.Println("blog loaded")
 
 	<-ctx.Done()
 }
@@ -13,7 +13,7 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer ui.Stop()
+	defer ui.Close()
 
 	err = ui.Run(ctx)
 	if err != nil {
@@ -3,28 +3,43 @@ package gofirefox
 import (
 	"bufio"
 	"context"
-	_ "embed"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
-	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
-	"strings"
+	"strconv"
 	"sync"
-	"sync/atomic"
 	"time"
 
-	"golang.org/x/net/websocket"
+	"github.com/unikiosk/go-firefox/prefs"
 )
 
 type h = map[string]interface{}
 
+// ErrConnectionClosed is returned for commands sent to the browser when the
+// connection is not established, or is lost before the result arrives.
+var ErrConnectionClosed = errors.New("gofirefox: connection to firefox closed")
+
+// Errors returned by Err, describing why firefox has ended.
+var (
+	// ErrClosed is returned when firefox was shut down with Close.
+	ErrClosed = errors.New("gofirefox: closed")
+	// ErrWindowClosed is returned when the user has closed the firefox window.
+	ErrWindowClosed = errors.New("gofirefox: window closed")
+	// ErrKilled is returned when the firefox process was killed.
+	ErrKilled = errors.New("gofirefox: firefox process killed")
+	// ErrCrashed is returned when firefox has exited unexpectedly.
+	ErrCrashed = errors.New("gofirefox: firefox crashed")
+)
+
+// closeTimeout is how long close waits for firefox to exit before escalating
+// to the next shutdown step.
+const closeTimeout = 5 * time.Second
+
 // Result is a struct for the resulting value of the JS expression or an error.
 type result struct {
 	Value json.RawMessage
@@ -33,28 +48,59 @@ type result struct {
 
 type bindingFunc func(args []json.RawMessage) (interface{}, error)
 
-// Msg is a struct for incoming messages (results and async events)
-type msg struct {
-	ID     int             `json:"id"`
-	Result json.RawMessage `json:"result"`
-	Error  json.RawMessage `json:"error"`
-	Method string          `json:"method"`
-	Params json.RawMessage `json:"params"`
-}
-
 type firefox struct {
 	config   Config
-	args     []string
-	userPref []string
+	args     []string // firefox arguments, except the URL
+	userPref []prefs.Pref
+	verifier *profileVerifier
+
+	profileSource ProfileSource
+	proto         Protocol
+
+	launcher Launcher
+
+	consoleEvents   *eventStream[ConsoleMessage]
+	exceptionEvents *eventStream[JSException]
+	idleEvents      *eventStream[IdleResetEvent]
+	subscriptions   *subscriptions
 
 	sync.Mutex
-	cmd      *exec.Cmd
-	ws       *websocket.Conn
-	id       int32
-	target   string
-	session  string
-	pending  map[int]chan result
+	home     string    // URL the UI was created with
+	url      string    // URL opened on start, the last URL of the main tab
+	inst     *instance // the running firefox
+	restarts int
+	target   string          // ID of the main tab
+	started  chan struct{}   // closed once the main tab is attached
+	targets  map[string]*tab // tabs by ID
+	tabs     []*tab          // tabs in the order they were opened
 	bindings map[string]bindingFunc
+	// interceptors of UI.Intercept, the first matching a request handles it
+	interceptors []*interceptor
+	// guard enforces the navigation policy, if any
+	guard  *navigationGuard
+	popups map[string]string // opener IDs by ID of the tabs opened by the page
+	// idle resets the session once the user is inactive, started on the
+	// first input
+	idle      *time.Timer
+	lastInput time.Time // zero until the first input after a reset
+
+	running    bool          // run was called
+	closing    chan struct{} // closed by close
+	done       chan struct{}
+	cause      error // why firefox is being shut down
+	err        error // why firefox has ended, set when done is closed
+	finishOnce sync.Once
+}
+
+// instance is a single run of firefox, replaced when firefox is restarted.
+type instance struct {
+	started bool          // the launcher has started firefox
+	reason  error         // why the instance is being shut down
+	exited  chan struct{} // closed when the instance has ended
+	err     error         // why the instance has ended, set when exited is closed
+
+	endOnce  sync.Once
+	exitOnce sync.Once
 }
 
 /* Firefox has a lot of configuration in profile, which is changing from release to release.
@@ -63,172 +109,311 @@ and attempts to use it. If profile does not exists - it will be double start
 (first start created profile, kinda bootstraps it),and second start configures it and uses.
 This is why we recommend some persistency of profile directory. */
 
-// new return new firefix instance. Arguments are passed to firefox executable.
-// userPref is a list of userPreferences (https://support.mozilla.org/en-US/kb/customizing-firefox-using-autoconfig) to be injected into firefox user configuration
-func new(arguments []string, userPref []string) (*firefox, error) {
-	// The first two IDs are used internally during the initialization
-	config, err := getConfig()
+// new return new firefix instance opening the given url.
+// config.Prefs are user preferences (https://support.mozilla.org/en-US/kb/customizing-firefox-using-autoconfig) to be injected into firefox user configuration
+func new(url string, config *Config) (*firefox, error) {
+	if err := config.prepare(); err != nil {
+		return nil, err
+	}
+	userPref, err := config.userPreferences()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := newProfileVerifier(config.ProfileSHA256, config.ProfilePublicKey)
 	if err != nil {
 		return nil, err
 	}
+	profileSource := config.ProfileSource
+	if profileSource == nil && config.ProfileLocationURL != "" {
+		profileSource, err = ParseProfileLocation(config.ProfileLocationURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if s, ok := profileSource.(loggingSource); ok {
+		profileSource = s.withLogger(config.logger)
+	}
 
+	arguments := []string{}
+	if config.Headless {
+		arguments = append(arguments, "--headless")
+	} else if config.Kiosk {
+		arguments = append(arguments, "--kiosk")
+	}
+	if config.WindowWidth > 0 && config.WindowHeight > 0 {
+		arguments = append(arguments, "--width", strconv.Itoa(config.WindowWidth))
+		arguments = append(arguments, "--height", strconv.Itoa(config.WindowHeight))
+	}
 	arguments = append(arguments, "--profile")
 	arguments = append(arguments, config.ProfileDir)
 	arguments = append(arguments, "--remote-debugging-port=0")
 	arguments = append(arguments, "--no-remote")
 
+	proto, err := newProtocol(config.Protocol, config.logger)
+	if err != nil {
+		return nil, err
+	}
+	guard, err := newNavigationGuard(config.Navigation, url)
+	if err != nil {
+		return nil, err
+	}
+	launcher := config.Launcher
+	if launcher == nil {
+		launcher = &ExecLauncher{Path: config.FirefoxBin}
+	}
+
 	c := &firefox{
-		id:       2,
 		config:   *config,
 		args:     arguments,
 		userPref: userPref,
-		pending:  map[int]chan result{},
-		bindings: map[string]bindingFunc{},
+		verifier: verifier,
+
+		profileSource: profileSource,
+		proto:         proto,
+		launcher:      launcher,
+		home:          url,
+		url:           url,
+		targets:       map[string]*tab{},
+		started:       make(chan struct{}),
+		bindings:      map[string]bindingFunc{},
+		guard:         guard,
+		popups:        map[string]string{},
+		closing:       make(chan struct{}),
+		done:          make(chan struct{}),
+
+		consoleEvents:   newEventStream[ConsoleMessage](config.EventBuffer),
+		exceptionEvents: newEventStream[JSException](config.EventBuffer),
+		idleEvents:      newEventStream[IdleResetEvent](config.EventBuffer),
+		subscriptions:   newSubscriptions(config.EventBuffer, config.logger),
 	}
 
 	return c, nil
 }
 
+// run starts firefox and blocks until it exits, restarting it according to
+// the restart policy. It returns nil if firefox was closed with close, by the
+// user or because ctx is done, and the reason otherwise.
 func (c *firefox) run(ctx context.Context) error {
-	defer c.stop()
-	err := c.bootstrapFirefoxProfile(ctx)
-	if err != nil {
-		return err
+	select {
+	case <-c.done:
+		return ErrClosed
+	default:
 	}
+	c.Lock()
+	c.running = true
+	c.Unlock()
 
-	// Start chrome process
-	c.cmd = exec.CommandContext(ctx, FirefoxExecutable(), c.args...)
-	c.cmd.Env = os.Environ()
-	pipe, err := c.cmd.StderrPipe()
-	if err != nil {
-		fmt.Printf("exec.v failed %v", err)
-		return err
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.close(ctx.Err())
+		case <-c.done:
+		}
+	}()
+
+	s := newSupervisor(c.config.Restart)
+loop:
+	for {
+		started := time.Now()
+		err := c.runInstance(ctx)
+		if cause := c.closeCause(); cause != nil {
+			c.finish(cause)
+			break
+		}
+		event, err := s.next(err, time.Since(started), time.Now())
+		if err != nil {
+			c.finish(err)
+			break
+		}
+
+		c.config.logger.Warn("restarting firefox", "restart", event.Restart, "backoff", event.Backoff, "cause", event.Cause)
+		if c.config.Restart.OnRestart != nil {
+			c.config.Restart.OnRestart(event)
+		}
+		select {
+		case <-c.closing:
+			c.finish(c.closeCause())
+			break loop
+		case <-time.After(event.Backoff):
+		}
+		c.Lock()
+		c.restarts++
+		c.Unlock()
 	}
-	if err := c.cmd.Start(); err != nil {
-		fmt.Printf("exec.Start failed %v", err)
+	<-c.done
+
+	switch err := c.reason(); {
+	case errors.Is(err, ErrClosed), errors.Is(err, ErrWindowClosed), err == ctx.Err():
+		return nil
+	default:
 		return err
 	}
+}
 
-	// Wait for websocket address to be printed to stderr
-	re := regexp.MustCompile(`^DevTools listening on (ws://.*?)\r?\n$`)
-	m, err := readUntilMatch(pipe, re)
-	if err != nil {
-		c.stop()
-		fmt.Printf("readUntilMatch failed %v", err)
-		return err
+// runInstance starts firefox and blocks until it has ended, and returns why.
+func (c *firefox) runInstance(ctx context.Context) error {
+	inst := &instance{exited: make(chan struct{})}
+	c.Lock()
+	c.inst = inst
+	// tabs of the previous instance are gone
+	for _, t := range c.tabs {
+		t.closed = true
+		t.update()
 	}
-	wsURL := m[1]
+	c.target, c.targets, c.tabs = "", map[string]*tab{}, nil
+	select {
+	case <-c.started:
+		c.started = make(chan struct{})
+	default:
+	}
+	c.Unlock()
 
-	// Open a websocket
-	c.ws, err = websocket.Dial(wsURL, "", "http://127.0.0.1")
-	if err != nil {
-		c.stop()
-		fmt.Printf("websocket.Dial failed %v", err)
-		return err
+	if err := c.start(ctx, inst); err != nil {
+		c.stop(inst)
+		c.exit(inst, err)
+	}
+	<-inst.exited
+	return inst.err
+}
+
+// start starts the firefox process, unless connecting to an existing one, and
+// attaches to its page.
+func (c *firefox) start(ctx context.Context, inst *instance) error {
+	c.Lock()
+	url, restarted := c.url, c.restarts > 0
+	c.Unlock()
+	wsURL := c.config.remoteURL
+	if wsURL == "" {
+		var err error
+		if wsURL, err = c.launch(ctx, inst, url); err != nil {
+			return err
+		}
 	}
 
-	// Find target and initialize session
-	c.target, err = c.findTarget()
+	// Connect and attach to the main tab
+	target, err := c.proto.Connect(ctx, wsURL, ProtocolEvents{
+		TabOpened:    c.tabOpened,
+		TabChanged:   c.tabChanged,
+		TabClosed:    func(target string) { c.tabClosed(inst, target) },
+		RealmCreated: c.realmCreated,
+		Console:      c.console,
+		Exception:    c.exception,
+
+		NavigationStarted: c.navigated,
+		LoadStateReached:  c.loadStateReached,
+		NavigationFailed:  c.navigationFailed,
+		Request:           c.request,
+		RequestPaused:     c.requestPaused,
+
+		Event:        c.subscriptions.publish,
+		Disconnected: func() { c.disconnected(inst) },
+	})
 	if err != nil {
-		c.stop()
-		fmt.Printf("c.findTarget failed %v", err)
+		c.config.logger.Error("failed to connect to firefox", "url", wsURL, "err", err)
 		return err
 	}
+	c.Lock()
+	c.target = target
+	c.Unlock()
 
-	c.session, err = c.startSession(c.target)
+	t, err := c.attach(ctx, target)
 	if err != nil {
-		c.stop()
-		fmt.Printf("startSession failed %v", err)
+		c.config.logger.Error("failed to attach to the main tab", "target", target, "err", err)
 		return err
 	}
-
-	go c.readLoop()
-	for method, args := range map[string]h{
-		"Page.enable":          nil,
-		"Target.setAutoAttach": {"autoAttach": true, "waitForDebuggerOnStart": false},
-		"Network.enable":       nil,
-		"Runtime.enable":       nil,
-		"Security.enable":      nil,
-		"Performance.enable":   nil,
-		"Log.enable":           nil,
-	} {
-		if _, err := c.send(method, args); err != nil {
-			c.stop()
-			c.cmd.Wait()
-			fmt.Printf("send failed %v", err)
-			return err
-		}
+	c.Lock()
+	close(c.started)
+	c.Unlock()
+	if restarted && c.config.remoteURL != "" && url != "" {
+		// launched firefox opens the last URL on start, existing firefox is
+		// navigated back to it
+		return t.Load(url)
 	}
+	return nil
+}
 
-	for {
-		state, err := c.cmd.Process.Wait()
-		switch {
-		case state != nil && state.Exited():
-			return nil
-		case state != nil && strings.EqualFold(state.String(), "signal: killed"):
-			return fmt.Errorf("process killed")
-		case err != nil:
-			return err
-		}
-		select {
-		case <-ctx.Done():
-			return nil
-		case <-time.After(time.Second):
+// launch starts the firefox process and returns the websocket URL it
+// announces.
+func (c *firefox) launch(ctx context.Context, inst *instance, url string) (string, error) {
+	err := c.bootstrapFirefoxProfile(ctx)
+	if err != nil {
+		return "", err
+	}
 
-		}
+	// Start firefox process
+	args := append([]string{}, c.config.Args...)
+	args = append(args, fmt.Sprintf("--new-window=%s", url))
+	args = append(args, c.args...)
+	if err := c.launcher.Start(ctx, args); err != nil {
+		c.config.logger.Error("failed to start firefox", "err", err)
+		return "", err
+	}
+	c.Lock()
+	inst.started = true
+	cause := inst.reason
+	c.Unlock()
+	if cause != nil {
+		// closed while starting
+		return "", cause
 	}
-}
 
-func (c *firefox) findTarget() (string, error) {
-	err := websocket.JSON.Send(c.ws, h{
-		"id": 0, "method": "Target.setDiscoverTargets", "params": h{"discover": true},
-	})
+	// Wait for websocket address to be printed to stderr
+	logger := c.config.logger
+	if p, ok := c.launcher.(pider); ok {
+		logger = logger.With("pid", p.Pid())
+	}
+	m, err := readUntilMatch(c.launcher.Stderr(), c.proto.Endpoint())
 	if err != nil {
+		logger.Error("firefox remote endpoint not found", "err", err)
 		return "", err
 	}
-	for {
-		m := msg{}
-		if err = websocket.JSON.Receive(c.ws, &m); err != nil {
-			return "", err
-		} else if m.Method == "Target.targetCreated" {
-			target := struct {
-				TargetInfo struct {
-					Type string `json:"type"`
-					ID   string `json:"targetId"`
-				} `json:"targetInfo"`
-			}{}
-			if err := json.Unmarshal(m.Params, &target); err != nil {
-				return "", err
-			} else if target.TargetInfo.Type == "page" {
-				return target.TargetInfo.ID, nil
-			}
+	logger.Info("firefox started", "url", m[1])
+
+	go func() {
+		err := c.launcher.Wait()
+		c.Lock()
+		cause := inst.reason
+		c.Unlock()
+		if cause == nil {
+			cause = exitCause(err)
 		}
-	}
+		logger.Info("firefox exited", "cause", cause)
+		c.stop(inst)
+		c.exit(inst, cause)
+	}()
+	return m[1], nil
 }
 
 func (c *firefox) bootstrapFirefoxProfile(ctx context.Context) error {
 	if err := func() error {
-		// create HTTP client
-		tr := &http.Transport{}
-		defer tr.CloseIdleConnections()
-		client := &http.Client{
-			Transport: tr,
-			Timeout:   30 * time.Second,
+		// copy the profile template, it provides user.js if loading fails
+		if err := copyProfileTemplate(c.config.ProfileTemplate, c.config.ProfileDir); err != nil {
+			return fmt.Errorf("failed to copy profile template: %s", err)
 		}
 
-		// download user.js file
+		// load user.js file
 		userJsPath := filepath.Join(c.config.ProfileDir, "user.js")
-		if c.config.ProfileLocationURL != "" {
-			log.Printf("downloading user.js %s --> %s", c.config.ProfileDir, userJsPath)
-			err := downloadFile(ctx, client, c.config.ProfileLocationURL, userJsPath)
+		if c.profileSource != nil {
+			c.config.logger.Info("loading user.js", "source", c.profileSource, "path", userJsPath)
+			ctx := ctx
+			if hasUserJS(c.config.ProfileTemplate) {
+				// don't keep an offline kiosk blank while retrying, the
+				// template works as well
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, profileFallbackTimeout)
+				defer cancel()
+			}
+			data, err := loadProfile(ctx, c.profileSource, newProfileCache(c.config.CacheDir), c.verifier, c.config.logger)
 			if err != nil {
-				return fmt.Errorf("failed to download user.js: %s", err)
+				c.config.logger.Warn("using the profile template", "err", err)
+			} else if err := writeFileAtomic(userJsPath, data); err != nil {
+				return err
 			}
 		}
 
 		// append/modify extra preferences to user.js via our script.
 		// function will update inplace.
-		err := configureDevTools(userJsPath, c.userPref)
+		err := configureDevTools(userJsPath, append(c.proto.Prefs(), c.userPref...))
 		if err != nil {
 			return fmt.Errorf("failed to configure %s - error: %s", userJsPath, err)
 		}
@@ -240,280 +425,369 @@ func (c *firefox) bootstrapFirefoxProfile(ctx context.Context) error {
 	return nil
 }
 
-func (c *firefox) startSession(target string) (string, error) {
-	err := websocket.JSON.Send(c.ws, h{
-		"id": 1, "method": "Target.attachToTarget", "params": h{"targetId": target},
-	})
-	if err != nil {
-		return "", err
-	}
-	for {
-		m := msg{}
-		if err = websocket.JSON.Receive(c.ws, &m); err != nil {
-			return "", err
-		} else if m.ID == 1 {
-			if m.Error != nil {
-				return "", errors.New("Target error: " + string(m.Error))
-			}
-			session := struct {
-				ID string `json:"sessionId"`
-			}{}
-			if err := json.Unmarshal(m.Result, &session); err != nil {
-				return "", err
-			}
-			return session.ID, nil
-		}
-	}
+// devToolsPrefs enable the remote debugging. Default we use, user preferences
+// may override them.
+var devToolsPrefs = []prefs.Pref{
+	{Name: "devtools.chrome.enabled", Value: true},
+	{Name: "devtools.debugger.prompt-connection", Value: false},
+	{Name: "devtools.debugger.remote-enabled", Value: true},
 }
 
-// values for devTools config:
-// user_pref("devtools.chrome.enabled", true);
-// user_pref("devtools.debugger.prompt-connection", false);
-// user_pref("devtools.debugger.remote-enabled", true,);
+// configureDevTools will merge the devtools config and user preferences into the profile
+func configureDevTools(prefFile string, userPref []prefs.Pref) error {
+	f, err := prefs.ReadFile(prefFile)
+	if os.IsNotExist(err) {
+		f = &prefs.File{}
+	} else if err != nil {
+		return err
+	}
 
-// configureDevTools will append the devtools config to the profile
-func configureDevTools(prefFile string, userPref []string) error {
-	f, err := os.Open(prefFile)
-	if err != nil {
+	overrides := append([]prefs.Pref{}, devToolsPrefs...)
+	overrides = append(overrides, userPref...)
+	if err := f.Merge(overrides...); err != nil {
 		return err
 	}
-	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
-	scanner.Split(bufio.ScanLines)
+	return f.WriteFile(prefFile, 0644)
+}
 
-	var lines []string
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+// bindingPayload is the message posted by the binding shim when the bound
+// function is called from JS.
+type bindingPayload struct {
+	Name string            `json:"name"`
+	Seq  int               `json:"seq"`
+	Args []json.RawMessage `json:"args"`
+}
+
+// bindingPayload returns the binding call carried by the console message, if
+// the message was posted by the binding shim.
+func (m ConsoleMessage) bindingPayload() (bindingPayload, bool) {
+	payload := bindingPayload{}
+	if len(m.Args) != 2 || m.Args[0].String() != bindingPrefix {
+		return payload, false
+	}
+	if err := json.Unmarshal([]byte(m.Args[1].String()), &payload); err != nil {
+		return payload, false
 	}
+	return payload, true
+}
 
-	// map[matcher-pattern]replacement. Default we use.
-	mods := map[string]string{
-		"devtools.chrome.enabled":             "user_pref(\"devtools.chrome.enabled\", true);",
-		"devtools.debugger.prompt-connection": "user_pref(\"devtools.debugger.prompt-connection\", false);",
-		"devtools.debugger.remote-enabled":    "user_pref(\"devtools.debugger.remote-enabled\", true);",
+// tabChanged attaches to the tabs opened by the page, e.g. popups, and
+// tracks the tab URLs.
+func (c *firefox) tabChanged(target, url string) {
+	if c.popupChanged(target, url) {
+		return
+	}
+	allowed := c.checkNavigation(target, url)
+	c.Lock()
+	t, ok := c.targets[target]
+	if ok {
+		t.url = url
+	}
+	if target == c.target && url != "" && allowed {
+		c.url = url
+	}
+	c.Unlock()
+	if !ok {
+		go func() {
+			if t, err := c.attach(context.Background(), target); err == nil {
+				c.Lock()
+				if t.url == "" {
+					t.url = url
+				}
+				c.Unlock()
+				c.checkNavigation(target, t.URL())
+			}
+		}()
 	}
+}
 
-	inMods := getMatchMods(userPref)
-	for matcher, replacement := range inMods {
-		mods[matcher] = replacement
+func (c *firefox) tabClosed(inst *instance, target string) {
+	c.removeTab(target)
+	c.Lock()
+	main := target == c.target
+	c.Unlock()
+	if main {
+		go c.end(inst, ErrWindowClosed)
 	}
+}
 
-	for matcher, replacement := range mods {
-		var exists bool
-		for idx, line := range lines {
-			if strings.Contains(line, matcher) {
-				lines[idx] = replacement
-				exists = true
+// realmCreated reinstalls all bindings and the input listener into the new
+// realm, so they survive page navigations.
+func (c *firefox) realmCreated(target, realm string) {
+	go func() {
+		for _, name := range c.bindingNames() {
+			if _, err := c.proto.Evaluate(context.Background(), target, realm, bindingExpr(name)); err != nil {
+				c.config.logger.Warn("failed to install binding", "target", target, "binding", name, "err", err)
 			}
 		}
-		if !exists {
-			lines = append(lines, replacement)
+		if c.config.IdleTimeout > 0 {
+			if _, err := c.proto.Evaluate(context.Background(), target, realm, idleExpr()); err != nil {
+				c.config.logger.Warn("failed to install input listener", "target", target, "err", err)
+			}
 		}
-	}
+	}()
+}
 
-	return os.WriteFile(prefFile, []byte(strings.Join(lines, "\n")), 0644)
-}
-
-// getMatchMods returns a map of user prefs to be added to the user.js file with a matcher-pattern to find if one exist
-func getMatchMods(userPref []string) map[string]string {
-	mods := make(map[string]string, len(userPref))
-	for _, pref := range userPref {
-		preff := strings.Replace(pref, "user_pref(", "", 1) // drop the user_pref(
-		preff = strings.Replace(preff, ");", "", 1)         // drop end identifier
-		parts := strings.Split(preff, ",")
-		identifier := strings.TrimSpace(parts[0])
-
-		mods[identifier] = pref
-	}
-	return mods
-
-}
-
-type targetMessageTemplate struct {
-	ID     int    `json:"id"`
-	Method string `json:"method"`
-	Params struct {
-		Name    string `json:"name"`
-		Payload string `json:"payload"`
-		ID      int    `json:"executionContextId"`
-		Args    []struct {
-			Type  string      `json:"type"`
-			Value interface{} `json:"value"`
-		} `json:"args"`
-	} `json:"params"`
-	Error struct {
-		Message string `json:"message"`
-	} `json:"error"`
-	Result json.RawMessage `json:"result"`
-}
-
-type targetMessage struct {
-	targetMessageTemplate
-	Result struct {
-		Result struct {
-			Type        string          `json:"type"`
-			Subtype     string          `json:"subtype"`
-			Description string          `json:"description"`
-			Value       json.RawMessage `json:"value"`
-			ObjectID    string          `json:"objectId"`
-		} `json:"result"`
-		Exception struct {
-			Exception struct {
-				Value json.RawMessage `json:"value"`
-			} `json:"exception"`
-		} `json:"exceptionDetails"`
-	} `json:"result"`
-}
-
-func (c *firefox) readLoop() {
-	for {
-		m := msg{}
-		if err := websocket.JSON.Receive(c.ws, &m); err != nil {
-			return
+func (c *firefox) console(target, realm string, msg ConsoleMessage) {
+	payload, ok := msg.bindingPayload()
+	if !ok {
+		msg.Tab = target
+		if msg.Timestamp.IsZero() {
+			msg.Timestamp = time.Now()
 		}
+		c.config.logger.With("source", "console").Log(context.Background(), msg.Level(), msg.String(), "target", target)
+		c.consoleEvents.publish(msg)
+		return
+	}
+	if payload.Name == idleInputName {
+		c.userInput()
+		return
+	}
 
-		if m.Method == "Target.receivedMessageFromTarget" {
-			params := struct {
-				SessionID string `json:"sessionId"`
-				Message   string `json:"message"`
-			}{}
-			err := json.Unmarshal(m.Params, &params)
-			if params.SessionID != c.session {
-				continue
-			}
-			if err != nil {
-				log.Println(err)
-			}
-
-			res := targetMessage{}
-			err = json.Unmarshal([]byte(params.Message), &res)
-			if err != nil {
-				log.Println(err)
+	c.Lock()
+	binding, ok := c.bindings[payload.Name]
+	c.Unlock()
+	if !ok {
+		return
+	}
+	jsString := func(v interface{}) string { b, _ := json.Marshal(v); return string(b) }
+	go func() {
+		result, error := "", `""`
+		if r, err := binding(payload.Args); err != nil {
+			error = jsString(err.Error())
+		} else if b, err := json.Marshal(r); err != nil {
+			error = jsString(err.Error())
+		} else {
+			result = string(b)
+		}
+		expr := fmt.Sprintf(`
+			if (%[4]s) {
+				window[%[1]s]['errors'].get(%[2]d)(%[4]s);
+			} else {
+				window[%[1]s]['callbacks'].get(%[2]d)(%[3]s);
 			}
+			window[%[1]s]['callbacks'].delete(%[2]d);
+			window[%[1]s]['errors'].delete(%[2]d);
+			`, jsString(payload.Name), payload.Seq, result, error)
+		c.proto.Evaluate(context.Background(), target, realm, expr)
+	}()
+}
 
-			if res.ID == 0 && res.Method == "Runtime.consoleAPICalled" || res.Method == "Runtime.exceptionThrown" {
-				log.Println(params.Message)
-			} else if res.ID == 0 && res.Method == "Runtime.bindingCalled" {
-				payload := struct {
-					Name string            `json:"name"`
-					Seq  int               `json:"seq"`
-					Args []json.RawMessage `json:"args"`
-				}{}
-				json.Unmarshal([]byte(res.Params.Payload), &payload)
+func (c *firefox) exception(target string, err *JSException) {
+	c.config.logger.With("source", "exception").Error(err.Error(), "target", target)
+	c.exceptionEvents.publish(*err)
+}
 
-				c.Lock()
-				binding, ok := c.bindings[res.Params.Name]
-				c.Unlock()
-				if ok {
-					jsString := func(v interface{}) string { b, _ := json.Marshal(v); return string(b) }
-					go func() {
-						result, error := "", `""`
-						if r, err := binding(payload.Args); err != nil {
-							error = jsString(err.Error())
-						} else if b, err := json.Marshal(r); err != nil {
-							error = jsString(err.Error())
-						} else {
-							result = string(b)
-						}
-						expr := fmt.Sprintf(`
-							if (%[4]s) {
-								window['%[1]s']['errors'].get(%[2]d)(%[4]s);
-							} else {
-								window['%[1]s']['callbacks'].get(%[2]d)(%[3]s);
-							}
-							window['%[1]s']['callbacks'].delete(%[2]d);
-							window['%[1]s']['errors'].delete(%[2]d);
-							`, payload.Name, payload.Seq, result, error)
-						c.send("Runtime.evaluate", h{"expression": expr, "contextId": res.Params.ID})
-					}()
-				}
-				continue
-			}
+/* Firefox does not implement Runtime.addBinding (https://bugzilla.mozilla.org/show_bug.cgi?id=1549487),
+so bindings are emulated: a JS shim installed into every execution context posts the call
+as a console message carrying bindingPrefix, which is picked up from the console events.
+The result is passed back by evaluating JS in the same realm. */
 
-			c.Lock()
-			resc, ok := c.pending[res.ID]
-			delete(c.pending, res.ID)
-			c.Unlock()
+// bindingPrefix marks console messages posted by the binding shim.
+const bindingPrefix = "__gofirefox_binding__"
 
-			if !ok {
-				continue
-			}
+// bindingScript installs window[name] which posts calls to Go and returns a
+// promise resolved when Go replies. Installing it twice is a no-op.
+const bindingScript = `(() => {
+	const bindingName = %[1]s;
+	if (window[bindingName] && window[bindingName]['__gofirefox']) {
+		return;
+	}
+	const binding = async (...args) => {
+		const seq = (binding['lastSeq'] || 0) + 1;
+		binding['lastSeq'] = seq;
+		const promise = new Promise((resolve, reject) => {
+			binding['callbacks'].set(seq, resolve);
+			binding['errors'].set(seq, reject);
+		});
+		console.debug(%[2]s, JSON.stringify({name: bindingName, seq, args}));
+		return promise;
+	};
+	binding['callbacks'] = new Map();
+	binding['errors'] = new Map();
+	binding['__gofirefox'] = true;
+	window[bindingName] = binding;
+})()`
+
+func bindingExpr(name string) string {
+	n, _ := json.Marshal(name)
+	p, _ := json.Marshal(bindingPrefix)
+	return fmt.Sprintf(bindingScript, n, p)
+}
 
-			if res.Error.Message != "" {
-				resc <- result{Err: errors.New(res.Error.Message)}
-			} else if res.Result.Exception.Exception.Value != nil {
-				resc <- result{Err: errors.New(string(res.Result.Exception.Exception.Value))}
-			} else if res.Result.Result.Type == "object" && res.Result.Result.Subtype == "error" {
-				resc <- result{Err: errors.New(res.Result.Result.Description)}
-			} else if res.Result.Result.Type != "" {
-				resc <- result{Value: res.Result.Result.Value}
-			} else {
-				res := targetMessageTemplate{}
-				json.Unmarshal([]byte(params.Message), &res)
-				resc <- result{Value: res.Result}
-			}
-		} else if m.Method == "Target.targetDestroyed" {
-			params := struct {
-				TargetID string `json:"targetId"`
-			}{}
-			json.Unmarshal(m.Params, &params)
-			if params.TargetID == c.target {
-				c.stop()
-				return
-			}
+func (c *firefox) bind(name string, f bindingFunc) error {
+	c.Lock()
+	c.bindings[name] = f
+	c.Unlock()
+	// tabs attached later install all bindings themselves
+	for _, t := range c.attachedTabs() {
+		if err := t.installBinding(context.Background(), name); err != nil {
+			return err
 		}
 	}
+	return nil
 }
 
-func (c *firefox) send(method string, params h) (json.RawMessage, error) {
-	id := atomic.AddInt32(&c.id, 1)
-	b, err := json.Marshal(h{"id": int(id), "method": method, "params": params})
-	if err != nil {
-		return nil, err
+func (c *firefox) bindingNames() []string {
+	c.Lock()
+	defer c.Unlock()
+	names := make([]string, 0, len(c.bindings))
+	for name := range c.bindings {
+		names = append(names, name)
+	}
+	return names
+}
+
+// installBinding makes the binding available in the current document and all
+// documents loaded later.
+func (t *tab) installBinding(ctx context.Context, name string) error {
+	return t.installScript(ctx, bindingExpr(name))
+}
+
+// installScript evaluates the JS source in the current document and all
+// documents loaded later.
+func (t *tab) installScript(ctx context.Context, source string) error {
+	if err := t.firefox.proto.AddScript(ctx, t.target, source); err != nil {
+		// not fatal, scripts are reinstalled when a realm is created
+		t.firefox.config.logger.Warn("failed to add script", "target", t.target, "err", err)
 	}
-	resc := make(chan result)
+	_, err := t.firefox.proto.Evaluate(ctx, t.target, "", source)
+	return err
+}
+
+// close gracefully shuts firefox down and waits until it exits, see end.
+// cause is recorded as the reason firefox has ended, unless there is one
+// already, and firefox is not restarted.
+func (c *firefox) close(cause error) {
 	c.Lock()
-	c.pending[int(id)] = resc
+	if c.cause == nil {
+		c.cause = cause
+		close(c.closing)
+	}
+	running, inst := c.running, c.inst
 	c.Unlock()
+	if !running {
+		// not started yet
+		c.finish(cause)
+		return
+	}
+	if inst != nil {
+		c.end(inst, cause)
+	}
+	<-c.done
+}
 
-	if err := websocket.JSON.Send(c.ws, h{
-		"id":     int(id),
-		"method": "Target.sendMessageToTarget",
-		"params": h{"message": string(b), "sessionId": c.session},
-	}); err != nil {
-		return nil, err
+// closeCause returns why firefox is being shut down, or nil if close was not
+// called.
+func (c *firefox) closeCause() error {
+	c.Lock()
+	defer c.Unlock()
+	return c.cause
+}
+
+// end gracefully shuts the instance down and waits until it has ended:
+// firefox is asked to close over the protocol first, then the process is
+// terminated and finally killed if it doesn't exit within closeTimeout. cause
+// is recorded as the reason the instance has ended, unless there is one
+// already.
+func (c *firefox) end(inst *instance, cause error) {
+	c.Lock()
+	if inst.reason == nil {
+		inst.reason = cause
+	}
+	started, reason := inst.started, inst.reason
+	c.Unlock()
+	if !started {
+		// not started yet, or connected to an existing firefox which is left
+		// running
+		c.stop(inst)
+		c.exit(inst, reason)
+		return
 	}
-	res := <-resc
 
-	return res.Value, res.Err
+	inst.endOnce.Do(func() {
+		steps := []func() error{c.proto.Close}
+		if t, ok := c.launcher.(terminator); ok {
+			steps = append(steps, t.Terminate)
+		}
+		steps = append(steps, c.launcher.Kill)
+		for _, step := range steps {
+			if err := step(); err != nil {
+				// e.g. signals are not supported on windows, escalate right away
+				continue
+			}
+			select {
+			case <-inst.exited:
+				return
+			case <-time.After(closeTimeout):
+			}
+		}
+	})
+	<-inst.exited
 }
 
-func (c *firefox) load(url string) error {
-	_, err := c.send("Page.navigate", h{"url": url})
-	return err
+// exit records why the instance has ended and closes exited.
+func (c *firefox) exit(inst *instance, err error) {
+	inst.exitOnce.Do(func() {
+		c.Lock()
+		inst.err = err
+		c.Unlock()
+		close(inst.exited)
+	})
 }
 
-func (c *firefox) stop() error {
-	if c.ws != nil {
-		if err := c.ws.Close(); err != nil {
-			return err
+// finish records why firefox has ended and closes done.
+func (c *firefox) finish(err error) {
+	c.finishOnce.Do(func() {
+		c.Lock()
+		c.err = err
+		if c.idle != nil {
+			c.idle.Stop()
 		}
-	}
-	defer func() {
-		if state := c.cmd.ProcessState; state == nil || !state.Exited() {
-			err := c.cmd.Process.Kill()
-			if err != nil {
-				log.Println(err)
-			}
+		c.Unlock()
+		c.subscriptions.close()
+		close(c.done)
+	})
+}
+
+// reason returns why firefox has ended, or nil if it is still running.
+func (c *firefox) reason() error {
+	c.Lock()
+	defer c.Unlock()
+	return c.err
+}
+
+// stop closes the connection and kills the firefox process of the instance.
+func (c *firefox) stop(inst *instance) {
+	c.proto.Disconnect()
+	c.Lock()
+	started := inst.started
+	c.Unlock()
+	if started {
+		if err := c.launcher.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			c.config.logger.Warn("failed to kill firefox", "err", err)
 		}
-	}()
-	return nil
+	}
+}
+
+// disconnected ends the instance connected to an existing firefox when the
+// connection is lost. Launched firefox ends when the process exits.
+func (c *firefox) disconnected(inst *instance) {
+	if c.config.remoteURL == "" {
+		return
+	}
+	c.Lock()
+	cause := inst.reason
+	c.Unlock()
+	if cause == nil {
+		cause = ErrConnectionClosed
+	}
+	c.exit(inst, cause)
 }
 
-func readUntilMatch(r io.ReadCloser, re *regexp.Regexp) ([]string, error) {
+func readUntilMatch(r io.Reader, re *regexp.Regexp) ([]string, error) {
 	br := bufio.NewReader(r)
-	defer r.Close()
 	for {
 		line, err := br.ReadString('\n')
 		if err != nil {
@@ -522,7 +796,6 @@ func readUntilMatch(r io.ReadCloser, re *regexp.Regexp) ([]string, error) {
 			}
 			return nil, err
 		} else if m := re.FindStringSubmatch(line); m != nil {
-			fmt.Println(line)
 			go io.Copy(ioutil.Discard, br)
 			return m, nil
 		}
@@ -1,5 +1,10 @@
 module github.com/unikiosk/go-firefox
 
-go 1.17
+go 1.21
 
-require golang.org/x/net v0.0.0-20200222125558-5a598a2470a0
+require (
+	golang.org/x/crypto v0.0.0-20200221231518-2aa609cf4a9d
+	golang.org/x/net v0.0.0-20200222125558-5a598a2470a0
+)
+
+require golang.org/x/sys v0.0.0-20190412213103-97732733099d // indirect
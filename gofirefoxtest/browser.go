@@ -0,0 +1,578 @@
+// Package gofirefoxtest provides a fake firefox for testing code using
+// go-firefox without firefox installed.
+//
+// The fake browser is a websocket server speaking the subset of the Chrome
+// DevTools Protocol go-firefox uses, and a stub executable announcing it on
+// stderr the same way firefox does:
+//
+//	b := gofirefoxtest.New(t, "about:blank")
+//	ui, err := gofirefox.NewWithOptions("about:blank", b.Options()...)
+//	go ui.Run(ctx)
+//	b.WaitCall("Runtime.enable", nil)
+//	b.EmitTarget(b.MainTarget(), "Runtime.consoleAPICalled", params)
+//
+// NewBiDi starts a browser speaking WebDriver BiDi instead, whose page
+// targets are the top-level browsing contexts.
+package gofirefoxtest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gofirefox "github.com/unikiosk/go-firefox"
+	"golang.org/x/net/websocket"
+)
+
+// WaitTimeout is how long WaitCall waits for the call.
+var WaitTimeout = 5 * time.Second
+
+// Call is a command received by the browser.
+type Call struct {
+	// Target is the ID of the target the command was sent to over its session,
+	// or empty for browser commands. With BiDi, it is the browsing context in
+	// the params of the command, if any.
+	Target string
+	Method string
+	Params json.RawMessage
+}
+
+// Handler returns the result of the command. The result is encoded to JSON,
+// and a *gofirefox.ProtocolError is sent with its code and data, or with its
+// message with BiDi.
+type Handler func(call Call) (interface{}, error)
+
+// Browser is a fake firefox. Its only page target is opened on start, and
+// more are opened with Target.createTarget. Commands without a handler
+// succeed with an empty result.
+type Browser struct {
+	t      testing.TB
+	server *httptest.Server
+	url    string
+	bidi   bool
+	exe    string
+	quit   string
+
+	writeMu sync.Mutex
+	ws      *websocket.Conn
+
+	mu       sync.Mutex
+	targets  map[string]string // URLs by target ID
+	sessions map[string]string // target IDs by session ID
+	lastID   int
+	handlers map[string]Handler
+	calls    []Call
+	changed  chan struct{} // closed when a call is received
+	exited   chan struct{} // closed when firefox started by Launcher exits
+	args     []string      // arguments firefox was started with by Launcher
+	crashed  bool          // firefox started by Launcher has crashed
+}
+
+// New starts the fake browser with a page target opened with the url. It is
+// closed when the test ends.
+func New(t testing.TB, url string) *Browser {
+	t.Helper()
+	return newBrowser(t, url, false)
+}
+
+// NewBiDi starts the fake browser speaking WebDriver BiDi, with a browsing
+// context opened with the url. It is closed when the test ends.
+func NewBiDi(t testing.TB, url string) *Browser {
+	t.Helper()
+	return newBrowser(t, url, true)
+}
+
+func newBrowser(t testing.TB, url string, bidi bool) *Browser {
+	t.Helper()
+	b := &Browser{
+		t:        t,
+		bidi:     bidi,
+		targets:  map[string]string{"page-1": url},
+		sessions: map[string]string{},
+		lastID:   1,
+		handlers: map[string]Handler{},
+		changed:  make(chan struct{}),
+	}
+	b.server = httptest.NewServer(websocket.Handler(b.serve))
+	b.url = "ws://" + b.server.Listener.Addr().String()
+	if !bidi {
+		b.url += "/devtools/browser/gofirefoxtest"
+	}
+
+	dir := t.TempDir()
+	b.quit = filepath.Join(dir, "quit")
+	exe, err := writeStub(dir, b.announcement(), b.quit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.exe = exe
+
+	t.Cleanup(b.Close)
+	return b
+}
+
+// URL returns the websocket URL of the browser. BiDi sessions are connected
+// to at its /session path.
+func (b *Browser) URL() string {
+	return b.url
+}
+
+// announcement returns the stderr line of firefox announcing the browser.
+func (b *Browser) announcement() string {
+	if b.bidi {
+		return "WebDriver BiDi listening on " + b.url
+	}
+	return "DevTools listening on " + b.url
+}
+
+// Executable returns the path to the stub firefox executable. It announces
+// the browser on stderr and exits when the browser is closed with
+// Browser.close, or the test ends.
+func (b *Browser) Executable() string {
+	return b.exe
+}
+
+// Options returns the options making the UI start the browser with Launcher,
+// a temporary profile and no user.js download. Logs are discarded, pass
+// gofirefox.WithSlog after them to see them.
+func (b *Browser) Options() []gofirefox.Option {
+	protocol := gofirefox.ProtocolCDP
+	if b.bidi {
+		protocol = gofirefox.ProtocolBiDi
+	}
+	return []gofirefox.Option{
+		gofirefox.WithLauncher(b.Launcher()),
+		gofirefox.WithProfileDir(b.t.TempDir()),
+		gofirefox.WithUserJSURL(""),
+		gofirefox.WithCacheDir(""),
+		gofirefox.WithKiosk(false),
+		gofirefox.WithProtocol(protocol),
+		gofirefox.WithSlog(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	}
+}
+
+// MainTarget returns the ID of the page target opened on start, the browsing
+// context with BiDi.
+func (b *Browser) MainTarget() string {
+	return "page-1"
+}
+
+// Close stops the browser and makes the stub executable exit.
+func (b *Browser) Close() {
+	b.exit()
+	b.server.Close()
+}
+
+// Handle sets the handler of the method, replacing the default behaviour.
+func (b *Browser) Handle(method string, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[method] = h
+}
+
+// Calls returns the received commands of the method, or all commands if
+// method is empty.
+func (b *Browser) Calls(method string) []Call {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	calls := []Call{}
+	for _, call := range b.calls {
+		if method == "" || call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// WaitCall waits until a command of the method matching match is received, and
+// fails the test if it isn't received within WaitTimeout. A nil match matches
+// all commands.
+func (b *Browser) WaitCall(method string, match func(Call) bool) Call {
+	b.t.Helper()
+	timeout := time.After(WaitTimeout)
+	for {
+		b.mu.Lock()
+		changed := b.changed
+		for _, call := range b.calls {
+			if call.Method == method && (match == nil || match(call)) {
+				b.mu.Unlock()
+				return call
+			}
+		}
+		b.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-timeout:
+			b.t.Fatalf("%s was not called", method)
+			return Call{}
+		}
+	}
+}
+
+// Emit sends the browser event, e.g. Target.targetDestroyed.
+func (b *Browser) Emit(method string, params interface{}) {
+	b.t.Helper()
+	if err := b.emit(method, params); err != nil {
+		b.t.Fatal(err)
+	}
+}
+
+func (b *Browser) emit(method string, params interface{}) error {
+	if b.bidi {
+		return b.send(map[string]interface{}{"type": "event", "method": method, "params": params})
+	}
+	return b.send(map[string]interface{}{"method": method, "params": params})
+}
+
+// EmitTarget sends the event over the session attached to the target, e.g.
+// Runtime.consoleAPICalled. BiDi events carry their browsing context in
+// their params, and are sent like with Emit.
+func (b *Browser) EmitTarget(target, method string, params interface{}) {
+	b.t.Helper()
+	if b.bidi {
+		b.Emit(method, params)
+		return
+	}
+	if err := b.sendTarget(target, map[string]interface{}{"method": method, "params": params}); err != nil {
+		b.t.Fatal(err)
+	}
+}
+
+// OpenPopup opens a page target with the url as if the page of the opener
+// target has opened it, e.g. with window.open, and returns its ID.
+func (b *Browser) OpenPopup(opener, url string) string {
+	b.t.Helper()
+	id := b.open(url)
+	if b.bidi {
+		info := contextInfo(id, url)
+		info["originalOpener"] = opener
+		b.Emit("browsingContext.contextCreated", info)
+		return id
+	}
+	info := targetInfo(id, url)
+	info["targetInfo"].(map[string]interface{})["openerId"] = opener
+	b.Emit("Target.targetCreated", info)
+	return id
+}
+
+// open adds a page target with the url and returns its ID.
+func (b *Browser) open(url string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	id := fmt.Sprintf("page-%d", b.lastID)
+	b.targets[id] = url
+	return id
+}
+
+// message is a command received by the browser.
+type message struct {
+	ID     int             `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+func (b *Browser) serve(ws *websocket.Conn) {
+	b.writeMu.Lock()
+	b.ws = ws
+	b.writeMu.Unlock()
+	for {
+		m := message{}
+		if err := websocket.JSON.Receive(ws, &m); err != nil {
+			return
+		}
+		if b.bidi {
+			result, err := b.call(Call{Target: bidiContext(m.Params), Method: m.Method, Params: m.Params})
+			b.replyBiDi(m.ID, result, err)
+			if m.Method == "browser.close" {
+				b.exit()
+				ws.Close()
+				return
+			}
+			continue
+		}
+		if m.Method == "Target.sendMessageToTarget" {
+			b.relay(m)
+			continue
+		}
+		result, err := b.call(Call{Method: m.Method, Params: m.Params})
+		b.reply(m.ID, result, err, b.send)
+		if m.Method == "Browser.close" {
+			b.exit()
+			ws.Close()
+			return
+		}
+	}
+}
+
+// relay handles the command sent over the session.
+func (b *Browser) relay(m message) {
+	params := struct {
+		SessionID string `json:"sessionId"`
+		Message   string `json:"message"`
+	}{}
+	json.Unmarshal(m.Params, &params)
+	b.mu.Lock()
+	target, ok := b.sessions[params.SessionID]
+	b.mu.Unlock()
+	if !ok {
+		b.reply(m.ID, nil, fmt.Errorf("unknown session %q", params.SessionID), b.send)
+		return
+	}
+	b.reply(m.ID, struct{}{}, nil, b.send)
+
+	inner := message{}
+	if err := json.Unmarshal([]byte(params.Message), &inner); err != nil {
+		b.t.Errorf("invalid message %q: %v", params.Message, err)
+		return
+	}
+	result, err := b.call(Call{Target: target, Method: inner.Method, Params: inner.Params})
+	b.reply(inner.ID, result, err, func(v interface{}) error { return b.sendTarget(target, v) })
+}
+
+// reply sends the result of the command. Errors are sent with the code -32000,
+// except *gofirefox.ProtocolError which is sent as is.
+func (b *Browser) reply(id int, result interface{}, err error, send func(v interface{}) error) {
+	perr := &gofirefox.ProtocolError{}
+	if errors.As(err, &perr) {
+		send(map[string]interface{}{"id": id, "error": map[string]interface{}{"code": perr.Code, "message": perr.Message, "data": perr.Data}})
+	} else if err != nil {
+		send(map[string]interface{}{"id": id, "error": map[string]interface{}{"code": -32000, "message": err.Error()}})
+	} else {
+		send(map[string]interface{}{"id": id, "result": result})
+	}
+}
+
+// replyBiDi sends the result of the BiDi command. Errors are sent as unknown
+// errors with their message.
+func (b *Browser) replyBiDi(id int, result interface{}, err error) {
+	perr := &gofirefox.ProtocolError{}
+	if errors.As(err, &perr) {
+		b.send(map[string]interface{}{"type": "error", "id": id, "error": "unknown error", "message": perr.Message})
+	} else if err != nil {
+		b.send(map[string]interface{}{"type": "error", "id": id, "error": "unknown error", "message": err.Error()})
+	} else {
+		b.send(map[string]interface{}{"type": "success", "id": id, "result": result})
+	}
+}
+
+// bidiContext returns the browsing context of the BiDi command params.
+func bidiContext(params json.RawMessage) string {
+	p := struct {
+		Context string `json:"context"`
+		Target  struct {
+			Context string `json:"context"`
+		} `json:"target"`
+	}{}
+	json.Unmarshal(params, &p)
+	if p.Context != "" {
+		return p.Context
+	}
+	return p.Target.Context
+}
+
+// call records the command and returns its result.
+func (b *Browser) call(call Call) (interface{}, error) {
+	b.mu.Lock()
+	b.calls = append(b.calls, call)
+	close(b.changed)
+	b.changed = make(chan struct{})
+	h, ok := b.handlers[call.Method]
+	b.mu.Unlock()
+	if ok {
+		return h(call)
+	}
+	if b.bidi {
+		return b.handleBiDi(call)
+	}
+	return b.handle(call)
+}
+
+// handle implements the default behaviour of the commands.
+func (b *Browser) handle(call Call) (interface{}, error) {
+	params := struct {
+		TargetID string `json:"targetId"`
+		URL      string `json:"url"`
+	}{}
+	json.Unmarshal(call.Params, &params)
+
+	switch call.Method {
+	case "Target.setDiscoverTargets":
+		b.mu.Lock()
+		targets := map[string]string{}
+		for id, url := range b.targets {
+			targets[id] = url
+		}
+		b.mu.Unlock()
+		for id, url := range targets {
+			b.emit("Target.targetCreated", targetInfo(id, url))
+		}
+	case "Target.attachToTarget":
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.targets[params.TargetID]; !ok {
+			return nil, fmt.Errorf("no target with given id found")
+		}
+		session := "session-" + params.TargetID
+		b.sessions[session] = params.TargetID
+		return map[string]interface{}{"sessionId": session}, nil
+	case "Target.createTarget":
+		id := b.open(params.URL)
+		b.emit("Target.targetCreated", targetInfo(id, params.URL))
+		return map[string]interface{}{"targetId": id}, nil
+	case "Target.closeTarget":
+		b.mu.Lock()
+		_, ok := b.targets[params.TargetID]
+		delete(b.targets, params.TargetID)
+		b.mu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("no target with given id found")
+		}
+		b.emit("Target.targetDestroyed", map[string]interface{}{"targetId": params.TargetID})
+		return map[string]interface{}{"success": true}, nil
+	case "Runtime.evaluate":
+		return map[string]interface{}{"result": map[string]interface{}{"type": "undefined"}}, nil
+	}
+	return struct{}{}, nil
+}
+
+func targetInfo(id, url string) map[string]interface{} {
+	return map[string]interface{}{
+		"targetInfo": map[string]interface{}{"targetId": id, "type": "page", "url": url},
+	}
+}
+
+// handleBiDi implements the default behaviour of the BiDi commands.
+func (b *Browser) handleBiDi(call Call) (interface{}, error) {
+	switch call.Method {
+	case "session.new":
+		return map[string]interface{}{"sessionId": "gofirefoxtest", "capabilities": map[string]interface{}{}}, nil
+	case "browsingContext.getTree":
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		contexts := []interface{}{}
+		// in the order they were opened
+		for n := 1; n <= b.lastID; n++ {
+			id := fmt.Sprintf("page-%d", n)
+			if url, ok := b.targets[id]; ok {
+				contexts = append(contexts, contextInfo(id, url))
+			}
+		}
+		return map[string]interface{}{"contexts": contexts}, nil
+	case "browsingContext.create":
+		id := b.open("about:blank")
+		b.emit("browsingContext.contextCreated", contextInfo(id, "about:blank"))
+		return map[string]interface{}{"context": id}, nil
+	case "browsingContext.close":
+		b.mu.Lock()
+		_, ok := b.targets[call.Target]
+		delete(b.targets, call.Target)
+		b.mu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("no such frame %q", call.Target)
+		}
+		b.emit("browsingContext.contextDestroyed", contextInfo(call.Target, ""))
+	case "script.evaluate", "script.callFunction":
+		return map[string]interface{}{"type": "success", "result": map[string]interface{}{"type": "undefined"}, "realm": "realm-" + call.Target}, nil
+	case "script.addPreloadScript":
+		return map[string]interface{}{"script": "script-" + call.Target}, nil
+	case "network.addIntercept":
+		return map[string]interface{}{"intercept": "intercept-" + call.Target}, nil
+	}
+	return struct{}{}, nil
+}
+
+func contextInfo(id, url string) map[string]interface{} {
+	return map[string]interface{}{"context": id, "url": url, "parent": nil, "children": []interface{}{}, "userContext": "default"}
+}
+
+// disconnect closes the connection, as if firefox has exited.
+func (b *Browser) disconnect() {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+	if b.ws != nil {
+		b.ws.Close()
+	}
+}
+
+func (b *Browser) send(v interface{}) error {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+	if b.ws == nil {
+		return fmt.Errorf("not connected")
+	}
+	return websocket.JSON.Send(b.ws, v)
+}
+
+// sendTarget sends the message over the session attached to the target.
+func (b *Browser) sendTarget(target string, v interface{}) error {
+	b.mu.Lock()
+	session := ""
+	for s, t := range b.sessions {
+		if t == target {
+			session = s
+		}
+	}
+	b.mu.Unlock()
+	if session == "" {
+		return fmt.Errorf("target %q is not attached", target)
+	}
+	msg, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.send(map[string]interface{}{
+		"method": "Target.receivedMessageFromTarget",
+		"params": map[string]interface{}{"sessionId": session, "targetId": target, "message": string(msg)},
+	})
+}
+
+// exit makes the stub executable and the firefox started by Launcher exit.
+func (b *Browser) exit() {
+	os.WriteFile(b.quit, nil, 0644)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.exited != nil {
+		select {
+		case <-b.exited:
+		default:
+			close(b.exited)
+		}
+	}
+}
+
+// writeStub writes the stub executable into dir. The stub prints the line
+// announcing the websocket URL the way firefox does, and waits until the quit
+// file exists.
+func writeStub(dir, announcement, quit string) (string, error) {
+	if runtime.GOOS == "windows" {
+		path := filepath.Join(dir, "firefox.bat")
+		script := strings.Join([]string{
+			"@echo off",
+			"echo " + announcement + " 1>&2",
+			":wait",
+			`if exist "` + quit + `" exit /b 0`,
+			"ping -n 1 -w 100 127.0.0.1 >nul",
+			"goto wait",
+		}, "\r\n") + "\r\n"
+		return path, os.WriteFile(path, []byte(script), 0755)
+	}
+	path := filepath.Join(dir, "firefox")
+	script := strings.Join([]string{
+		"#!/bin/sh",
+		"echo '" + announcement + "' >&2",
+		`while [ ! -e '` + quit + `' ]; do sleep 0.05; done`,
+	}, "\n") + "\n"
+	return path, os.WriteFile(path, []byte(script), 0755)
+}
@@ -0,0 +1,77 @@
+package gofirefoxtest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	gofirefox "github.com/unikiosk/go-firefox"
+)
+
+// Launcher returns the launcher starting the browser in process, without the
+// stub executable. Firefox started by it exits when the browser is closed
+// with Browser.close, or killed.
+func (b *Browser) Launcher() gofirefox.Launcher {
+	return &launcher{b: b}
+}
+
+// Args returns the arguments firefox was last started with by Launcher.
+func (b *Browser) Args() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string{}, b.args...)
+}
+
+// Crash makes the firefox started by Launcher exit with an error, and closes
+// the connection.
+func (b *Browser) Crash() {
+	b.mu.Lock()
+	b.crashed = true
+	b.mu.Unlock()
+	b.exit()
+	b.disconnect()
+}
+
+type launcher struct {
+	b      *Browser
+	exited chan struct{}
+}
+
+func (l *launcher) Start(ctx context.Context, args []string) error {
+	l.b.mu.Lock()
+	defer l.b.mu.Unlock()
+	l.exited = make(chan struct{})
+	l.b.exited = l.exited
+	l.b.crashed = false
+	l.b.args = append([]string{}, args...)
+	return nil
+}
+
+func (l *launcher) Stderr() io.Reader {
+	return strings.NewReader(l.b.announcement() + "\n")
+}
+
+func (l *launcher) Wait() error {
+	<-l.exited
+	l.b.mu.Lock()
+	defer l.b.mu.Unlock()
+	if l.b.crashed {
+		return errors.New("firefox crashed")
+	}
+	return nil
+}
+
+func (l *launcher) Kill() error {
+	l.b.mu.Lock()
+	defer l.b.mu.Unlock()
+	select {
+	case <-l.exited:
+		return os.ErrProcessDone
+	default:
+		close(l.exited)
+	}
+	l.b.disconnect()
+	return nil
+}
@@ -4,9 +4,10 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
 	"time"
 )
 
@@ -16,42 +17,138 @@ var headers = map[string]string{
 	"DNT":             "1",
 }
 
-func downloadFile(ctx context.Context, client *http.Client, fileURL, filePath string) error {
-	resp, err := openURLHTTP(ctx, client, fileURL)
+// httpSource downloads user.js over HTTP(S), retrying failed requests. The
+// cached copy is revalidated with ETag/Last-Modified.
+type httpSource struct {
+	url    string
+	client *http.Client
+	logger *slog.Logger // slog.Default() if nil
+}
+
+// ProfileFromURL returns the source downloading user.js from the http(s) URL.
+// The minisign signature is downloaded from the URL with the .minisig suffix.
+func ProfileFromURL(url string) ProfileSource {
+	return &httpSource{url: url}
+}
+
+func (s *httpSource) String() string {
+	return s.url
+}
+
+// withLogger returns a copy of the source logging to the logger.
+func (s *httpSource) withLogger(logger *slog.Logger) ProfileSource {
+	c := *s
+	c.logger = logger
+	return &c
+}
+
+func (s *httpSource) Fetch(ctx context.Context, signature bool) ([]byte, []byte, error) {
+	logger := s.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	entry, err := s.fetchIfModified(ctx, nil, signature, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	return entry.data, entry.Signature, nil
+}
+
+// fetchIfModified downloads user.js. It returns cached if the server replies
+// it is not modified.
+func (s *httpSource) fetchIfModified(ctx context.Context, cached *cacheEntry, signature bool, logger *slog.Logger) (*cacheEntry, error) {
+	client := s.client
+	if client == nil {
+		// create HTTP client
+		tr := &http.Transport{}
+		defer tr.CloseIdleConnections()
+		client = &http.Client{
+			Transport: tr,
+			Timeout:   30 * time.Second,
+		}
+	}
+
+	header := http.Header{}
+	if cached != nil && cached.ETag != "" {
+		header.Set("If-None-Match", cached.ETag)
+	}
+	if cached != nil && cached.LastModified != "" {
+		header.Set("If-Modified-Since", cached.LastModified)
+	}
+	resp, err := openURLHTTP(ctx, client, s.url, header, logger)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode > 399 {
-		return fmt.Errorf("status_code=%d", resp.StatusCode)
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		return cached, nil
 	}
-	f, err := os.Create(filePath)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("status_code=%d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxProfileSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s - error: %s", s.url, err)
+	}
+	entry := &cacheEntry{
+		URL:          s.url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		data:         data,
+	}
+
+	if signature {
+		if entry.Signature, err = downloadSignature(ctx, client, s.url+signatureSuffix, logger); err != nil {
+			return nil, fmt.Errorf("failed to download signature: %s", err)
+		}
+	}
+	return entry, nil
+}
+
+func downloadSignature(ctx context.Context, client *http.Client, sigURL string, logger *slog.Logger) ([]byte, error) {
+	resp, err := openURLHTTP(ctx, client, sigURL, nil, logger)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("status_code=%d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxProfileSize))
+}
+
+// writeFileAtomic writes into a temporary file first and renames it, so the
+// previous file is kept if writing fails.
+func writeFileAtomic(filePath string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(filePath), filepath.Base(filePath)+".*")
 	if err != nil {
 		return fmt.Errorf("failed to create file: %s", err)
 	}
-	defer f.Close()
-	_, err = io.Copy(f, resp.Body)
+	defer os.Remove(f.Name())
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
 	if err != nil {
 		return fmt.Errorf("failed to write to file %s - error: %s", filePath, err)
 	}
-	return nil
+	return os.Rename(f.Name(), filePath)
 }
 
 const userAgent = "Mozilla/5.0 (Windows NT 10.0; rv:91.0) Gecko/20100101 Firefox/91.0"
 
-func openURLHTTP(ctx context.Context, client *http.Client, pageURL string) (*http.Response, error) {
+func openURLHTTP(ctx context.Context, client *http.Client, pageURL string, header http.Header, logger *slog.Logger) (*http.Response, error) {
 	var resp *http.Response
 	var err error
 	for attempt := 0; attempt < 5; attempt++ {
-		select {
-		case <-ctx.Done():
-			return nil, fmt.Errorf("context canceled")
-		default:
-		}
 		if attempt > 0 {
 			dur := time.Duration(attempt*attempt) * time.Second
-			log.Printf("HTTP request failed: %s - retrying in %v", err, dur)
-			time.Sleep(dur)
+			logger.Warn("HTTP request failed, retrying", "url", pageURL, "err", err, "retry_in", dur)
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("%s, giving up: %w", err, ctx.Err())
+			case <-time.After(dur):
+			}
 		}
 		var req *http.Request
 		req, err = http.NewRequestWithContext(ctx, "GET", pageURL, nil)
@@ -63,6 +160,9 @@ func openURLHTTP(ctx context.Context, client *http.Client, pageURL string) (*htt
 		for headerKey, headerValue := range headers {
 			req.Header.Set(headerKey, headerValue)
 		}
+		for headerKey, headerValues := range header {
+			req.Header[headerKey] = headerValues
+		}
 		resp, err = client.Do(req)
 		if err != nil {
 			err = fmt.Errorf("HTTP request failed: %s", err)
@@ -0,0 +1,176 @@
+package gofirefox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// idleResetTimeout is how long resetting the idle session may take.
+const idleResetTimeout = 30 * time.Second
+
+// IdleResetEvent is sent on UI.IdleResets when the session was reset after
+// the user was inactive, see WithIdleReset.
+type IdleResetEvent struct {
+	// LastInput is when the user input was last seen.
+	LastInput time.Time
+	// Time is when the session was reset.
+	Time time.Time
+	// HomeURL is the URL the main tab was navigated to.
+	HomeURL string
+	// Err is why the reset has failed, if it has. The steps which could be
+	// taken were taken.
+	Err error
+}
+
+/* User input is reported by a listener installed into every document like the
+bindings, which posts a binding payload for idleInputName at most once per
+second. The payload is handled by console without a reply. */
+
+// idleInputName is the binding name of the user input reports.
+const idleInputName = "__gofirefox_input__"
+
+// idleScript reports the user input to Go. Installing it twice is a no-op.
+const idleScript = `(() => {
+	const name = %[1]s;
+	if (window[name]) {
+		return;
+	}
+	window[name] = true;
+	let last = 0;
+	const report = () => {
+		const now = Date.now();
+		if (now - last < 1000) {
+			return;
+		}
+		last = now;
+		console.debug(%[2]s, JSON.stringify({name, seq: 0, args: []}));
+	};
+	for (const type of ['keydown', 'pointerdown', 'pointermove', 'touchstart', 'wheel']) {
+		window.addEventListener(type, report, {capture: true, passive: true});
+	}
+})()`
+
+func idleExpr() string {
+	n, _ := json.Marshal(idleInputName)
+	p, _ := json.Marshal(bindingPrefix)
+	return fmt.Sprintf(idleScript, n, p)
+}
+
+// clearStorageScript clears the storage of the origin of the document, which
+// the remote protocols cannot clear. Storage which is not available, e.g. on
+// about:blank, is skipped.
+const clearStorageScript = `(async () => {
+	try {
+		localStorage.clear();
+		sessionStorage.clear();
+	} catch (e) {}
+	try {
+		for (const db of await indexedDB.databases()) {
+			indexedDB.deleteDatabase(db.name);
+		}
+	} catch (e) {}
+	try {
+		for (const key of await caches.keys()) {
+			await caches.delete(key);
+		}
+	} catch (e) {}
+	try {
+		for (const registration of await navigator.serviceWorker.getRegistrations()) {
+			await registration.unregister();
+		}
+	} catch (e) {}
+})()`
+
+// idleHome returns the URL the idle session is reset to.
+func (c *firefox) idleHome() string {
+	if c.config.IdleHomeURL != "" {
+		return c.config.IdleHomeURL
+	}
+	return c.home
+}
+
+// userInput restarts the idle timeout, the session is reset once it expires.
+func (c *firefox) userInput() {
+	if c.config.IdleTimeout <= 0 {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.lastInput = time.Now()
+	if c.idle == nil {
+		c.idle = time.AfterFunc(c.config.IdleTimeout, c.idleExpired)
+	} else {
+		c.idle.Reset(c.config.IdleTimeout)
+	}
+}
+
+// idleExpired resets the session if there was user input since the last
+// reset and none within the idle timeout.
+func (c *firefox) idleExpired() {
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+	c.Lock()
+	lastInput := c.lastInput
+	if lastInput.IsZero() || time.Since(lastInput) < c.config.IdleTimeout {
+		// reset already, or input was reported while expiring
+		c.Unlock()
+		return
+	}
+	c.lastInput = time.Time{}
+	c.Unlock()
+
+	home := c.idleHome()
+	c.config.logger.Info("resetting idle session", "idle", time.Since(lastInput).Round(time.Second), "url", home)
+	ctx, cancel := context.WithTimeout(context.Background(), idleResetTimeout)
+	defer cancel()
+	err := c.resetSession(ctx, home)
+	if err != nil {
+		c.config.logger.Warn("failed to reset idle session", "err", err)
+	}
+	c.idleEvents.publish(IdleResetEvent{LastInput: lastInput, Time: time.Now(), HomeURL: home, Err: err})
+}
+
+// resetSession replaces the main tab with a new one, as the remote protocols
+// cannot clear the back history of a tab, clears the storage of the documents
+// of the other tabs and closes them, clears the cookies and cache, and loads
+// the URL in the new main tab. All steps are taken even if some fail.
+func (c *firefox) resetSession(ctx context.Context, url string) error {
+	main := c.mainTab()
+	if main == nil {
+		return ErrConnectionClosed
+	}
+	errs := []error{}
+	if t, err := c.newTab(ctx, "about:blank"); err != nil {
+		errs = append(errs, fmt.Errorf("failed to open a new main tab: %w", err))
+	} else {
+		main = t
+		c.setMainTab(main)
+		if err := c.proto.ActivateTab(ctx, main.target); err != nil {
+			errs = append(errs, fmt.Errorf("failed to activate the new main tab: %w", err))
+		}
+	}
+	for _, t := range c.attachedTabs() {
+		if t != main {
+			// storage is per origin, the documents left open are the ones used
+			if _, err := t.Eval(ctx, clearStorageScript); err != nil {
+				errs = append(errs, fmt.Errorf("failed to clear storage: %w", err))
+			}
+			if err := t.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to close tab: %w", err))
+			}
+		}
+	}
+	if err := c.proto.ClearBrowsingData(ctx, main.target); err != nil {
+		errs = append(errs, fmt.Errorf("failed to clear browsing data: %w", err))
+	}
+	if err := main.Load(url); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
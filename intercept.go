@@ -0,0 +1,214 @@
+package gofirefox
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// ResourceType is how the page uses the requested resource.
+type ResourceType string
+
+const (
+	ResourceDocument   ResourceType = "Document"
+	ResourceStylesheet ResourceType = "Stylesheet"
+	ResourceImage      ResourceType = "Image"
+	ResourceMedia      ResourceType = "Media"
+	ResourceFont       ResourceType = "Font"
+	ResourceScript     ResourceType = "Script"
+	ResourceXHR        ResourceType = "XHR"
+	ResourceFetch      ResourceType = "Fetch"
+	ResourceWebSocket  ResourceType = "WebSocket"
+	ResourceOther      ResourceType = "Other"
+)
+
+// ErrRequestHandled is returned when an intercepted request is continued,
+// fulfilled or failed more than once.
+var ErrRequestHandled = errors.New("gofirefox: request already handled")
+
+// InterceptedRequest is a request paused by UI.Intercept until it is
+// continued, fulfilled or failed.
+type InterceptedRequest struct {
+	// Tab is the ID of the tab the request was sent from.
+	Tab          string
+	URL          string
+	Method       string
+	Headers      map[string]string
+	PostData     string
+	ResourceType ResourceType
+
+	// id identifies the paused request for the protocol
+	id      string
+	proto   Protocol
+	handled atomic.Bool
+	// navigation is set if the request loads the document of the tab
+	navigation bool
+}
+
+// Continue sends the request unchanged.
+func (r *InterceptedRequest) Continue() error {
+	return r.ContinueWithHeaders(nil)
+}
+
+// ContinueWithHeaders sends the request with the headers instead of its own,
+// or unchanged if headers is nil.
+func (r *InterceptedRequest) ContinueWithHeaders(headers map[string]string) error {
+	if !r.handled.CompareAndSwap(false, true) {
+		return ErrRequestHandled
+	}
+	return r.proto.ContinueRequest(context.Background(), r.Tab, r.id, headers)
+}
+
+// Fulfill responds to the request with the status, headers and body, without
+// sending it.
+func (r *InterceptedRequest) Fulfill(status int, headers map[string]string, body []byte) error {
+	if !r.handled.CompareAndSwap(false, true) {
+		return ErrRequestHandled
+	}
+	return r.proto.FulfillRequest(context.Background(), r.Tab, r.id, status, headers, body)
+}
+
+// Fail fails the request with a network error, without sending it.
+func (r *InterceptedRequest) Fail() error {
+	if !r.handled.CompareAndSwap(false, true) {
+		return ErrRequestHandled
+	}
+	return r.proto.FailRequest(context.Background(), r.Tab, r.id)
+}
+
+// interceptor is the handler of the requests matching the URL pattern and
+// resource types.
+type interceptor struct {
+	pattern string
+	re      *regexp.Regexp
+	types   []ResourceType
+	handler func(*InterceptedRequest)
+}
+
+func (i *interceptor) match(r *InterceptedRequest) bool {
+	if !i.re.MatchString(r.URL) {
+		return false
+	}
+	if len(i.types) == 0 {
+		return true
+	}
+	for _, t := range i.types {
+		if t == r.ResourceType {
+			return true
+		}
+	}
+	return false
+}
+
+// globRegexp returns the regexp matching the whole URL with the glob pattern:
+// '*' matches any characters, '?' any one character, and '\' escapes the next
+// character. It is the pattern syntax of Fetch.enable.
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	re := strings.Builder{}
+	re.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '*':
+			re.WriteString(".*")
+		case c == '?':
+			re.WriteString(".")
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			re.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			re.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	re.WriteString("$")
+	return regexp.Compile(re.String())
+}
+
+// intercept adds the interceptor and makes the tabs pause the requests it
+// matches. Tabs attached later are intercepted on attach.
+func (c *firefox) intercept(i *interceptor) error {
+	c.Lock()
+	c.interceptors = append(c.interceptors, i)
+	c.Unlock()
+	return c.updateIntercepts()
+}
+
+// removeInterceptor removes the interceptor, requests it matched are no longer
+// paused.
+func (c *firefox) removeInterceptor(i *interceptor) {
+	c.Lock()
+	for n := range c.interceptors {
+		if c.interceptors[n] == i {
+			c.interceptors = append(c.interceptors[:n:n], c.interceptors[n+1:]...)
+			break
+		}
+	}
+	c.Unlock()
+	if err := c.updateIntercepts(); err != nil {
+		c.config.logger.Warn("failed to stop intercepting requests", "pattern", i.pattern, "err", err)
+	}
+}
+
+// interceptPatterns returns the URL patterns of the interceptors.
+func (c *firefox) interceptPatterns() []string {
+	c.Lock()
+	defer c.Unlock()
+	seen := map[string]bool{}
+	patterns := []string{}
+	for _, i := range c.interceptors {
+		if !seen[i.pattern] {
+			seen[i.pattern] = true
+			patterns = append(patterns, i.pattern)
+		}
+	}
+	sort.Strings(patterns)
+	return patterns
+}
+
+// updateIntercepts sets the URL patterns of the interceptors to all tabs. The
+// navigations are paused too if the navigation policy restricts them.
+func (c *firefox) updateIntercepts() error {
+	patterns := c.interceptPatterns()
+	for _, t := range c.attachedTabs() {
+		if err := c.proto.Intercept(context.Background(), t.target, patterns, c.guard.restricts()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requestPaused fails the navigations blocked by the navigation policy, before
+// the blocked page is loaded, and passes the other requests to the first
+// interceptor matching them. Requests are continued once the handler returns,
+// unless it has handled them.
+func (c *firefox) requestPaused(target string, r *InterceptedRequest) {
+	r.Tab, r.proto = target, c.proto
+	if r.navigation && !c.checkNavigation(target, r.URL) {
+		go func() {
+			if err := r.Fail(); err != nil {
+				c.config.logger.Warn("failed to block the navigation", "target", target, "url", r.URL, "err", err)
+			}
+		}()
+		return
+	}
+	c.Lock()
+	var handler func(*InterceptedRequest)
+	for _, i := range c.interceptors {
+		if i.match(r) {
+			handler = i.handler
+			break
+		}
+	}
+	c.Unlock()
+
+	go func() {
+		if handler != nil {
+			handler(r)
+		}
+		if err := r.Continue(); err != nil && !errors.Is(err, ErrRequestHandled) {
+			c.config.logger.Warn("failed to continue the request", "target", target, "url", r.URL, "err", err)
+		}
+	}()
+}
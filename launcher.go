@@ -0,0 +1,131 @@
+package gofirefox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Launcher starts the firefox process, e.g. as a child process, in a container
+// or under a supervisor. Start may be called again after the process has
+// exited.
+type Launcher interface {
+	// Start starts firefox with the arguments.
+	Start(ctx context.Context, args []string) error
+	// Stderr returns the stderr of the started firefox, where it announces
+	// the websocket URL of the remote protocol.
+	Stderr() io.Reader
+	// Wait waits until firefox exits. It returns nil if firefox has exited
+	// successfully, and *exec.ExitError if it is available otherwise.
+	Wait() error
+	// Kill kills firefox. It returns os.ErrProcessDone if it has already
+	// exited.
+	Kill() error
+}
+
+// terminator is implemented by launchers which can ask firefox to exit
+// gracefully, before it is killed.
+type terminator interface {
+	Terminate() error
+}
+
+// pider is implemented by launchers which know the process ID of firefox, it
+// is added to the logs.
+type pider interface {
+	Pid() int
+}
+
+// ExecLauncher starts firefox as a child process. It is used by default.
+type ExecLauncher struct {
+	// Path is the firefox executable, or a wrapper script passing its
+	// arguments to firefox.
+	Path string
+	// Env is the environment of the process, the current environment if nil.
+	Env []string
+
+	cmd    *exec.Cmd
+	stderr io.Reader
+}
+
+// errNotStarted is returned by the ExecLauncher methods called before firefox
+// was started.
+var errNotStarted = errors.New("firefox was not started")
+
+// Start starts firefox, unless ctx is already done. The process is not bound
+// to ctx: when the context of Run is done, firefox is asked to close and
+// terminated before it is killed, like on Close.
+func (l *ExecLauncher) Start(ctx context.Context, args []string) error {
+	l.cmd, l.stderr = nil, nil
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	cmd := exec.Command(l.Path, args...)
+	cmd.Env = l.Env
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	l.cmd, l.stderr = cmd, stderr
+	return nil
+}
+
+func (l *ExecLauncher) Stderr() io.Reader {
+	return l.stderr
+}
+
+func (l *ExecLauncher) Wait() error {
+	if l.cmd == nil {
+		return errNotStarted
+	}
+	return l.cmd.Wait()
+}
+
+func (l *ExecLauncher) Kill() error {
+	if l.cmd == nil {
+		return errNotStarted
+	}
+	return l.cmd.Process.Kill()
+}
+
+// Pid returns the process ID of the started firefox, or 0 if it was not
+// started.
+func (l *ExecLauncher) Pid() int {
+	if l.cmd == nil {
+		return 0
+	}
+	return l.cmd.Process.Pid
+}
+
+// Terminate sends SIGTERM to firefox. Signals are not supported on windows.
+func (l *ExecLauncher) Terminate() error {
+	if l.cmd == nil {
+		return errNotStarted
+	}
+	return l.cmd.Process.Signal(syscall.SIGTERM)
+}
+
+// exitCause returns why the firefox process, which was not asked to shut
+// down, has exited.
+func exitCause(err error) error {
+	exitErr := &exec.ExitError{}
+	switch {
+	case err == nil:
+		return ErrWindowClosed
+	case !errors.As(err, &exitErr):
+		return fmt.Errorf("%w: %s", ErrCrashed, err)
+	case exitErr.ProcessState.String() == "signal: killed":
+		return ErrKilled
+	default:
+		return fmt.Errorf("%w: %s", ErrCrashed, exitErr.ProcessState)
+	}
+}
@@ -0,0 +1,263 @@
+package gofirefox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LoadState is how far a page is loaded.
+type LoadState string
+
+const (
+	// LoadStateDOMContentLoaded is reached when the document is parsed,
+	// before its images, stylesheets and frames are loaded.
+	LoadStateDOMContentLoaded LoadState = "domcontentloaded"
+	// LoadStateLoad is reached when the document and its resources are
+	// loaded.
+	LoadStateLoad LoadState = "load"
+	// LoadStateNetworkIdle is reached when the page is loaded and no request
+	// was sent or completed for NetworkIdleTime.
+	LoadStateNetworkIdle LoadState = "networkidle"
+)
+
+// NetworkIdleTime is how long the network must be quiet for
+// LoadStateNetworkIdle.
+var NetworkIdleTime = 500 * time.Millisecond
+
+// rank orders the load states.
+func (s LoadState) rank() int {
+	switch s {
+	case LoadStateDOMContentLoaded:
+		return 1
+	case LoadStateLoad:
+		return 2
+	case LoadStateNetworkIdle:
+		return 3
+	default:
+		return 0
+	}
+}
+
+var (
+	// ErrNameNotResolved is the cause of navigations failing because the
+	// host name could not be resolved.
+	ErrNameNotResolved = errors.New("gofirefox: name not resolved")
+	// ErrHTTPStatus is the cause of navigations whose document was loaded
+	// with an HTTP error status.
+	ErrHTTPStatus = errors.New("gofirefox: HTTP error status")
+	// ErrNavigationAborted is the cause of navigations which were stopped
+	// or replaced by another navigation.
+	ErrNavigationAborted = errors.New("gofirefox: navigation aborted")
+	// ErrNavigationFailed is the cause of navigations failing for other
+	// reasons, e.g. a refused connection.
+	ErrNavigationFailed = errors.New("gofirefox: navigation failed")
+)
+
+// NavigationError is returned when loading a page fails. Its cause is
+// ErrNameNotResolved, ErrHTTPStatus, ErrNavigationAborted or
+// ErrNavigationFailed:
+//
+//	if errors.Is(err, gofirefox.ErrHTTPStatus) { ... }
+type NavigationError struct {
+	// URL is the URL which failed to load.
+	URL string
+	// StatusCode is the HTTP status of ErrHTTPStatus.
+	StatusCode int
+	// Text is the error reported by the browser, e.g. NS_ERROR_UNKNOWN_HOST.
+	Text string
+	Err  error
+}
+
+// newNavigationError returns the navigation error for the error reported by
+// the browser.
+func newNavigationError(url, text string) *NavigationError {
+	err := ErrNavigationFailed
+	switch {
+	case strings.Contains(text, "NS_ERROR_UNKNOWN_HOST"), strings.Contains(text, "NS_ERROR_UNKNOWN_PROXY_HOST"),
+		strings.Contains(text, "ERR_NAME_NOT_RESOLVED"):
+		err = ErrNameNotResolved
+	case strings.Contains(text, "NS_BINDING_ABORTED"), strings.Contains(text, "ERR_ABORTED"):
+		err = ErrNavigationAborted
+	}
+	return &NavigationError{URL: url, Text: text, Err: err}
+}
+
+func (e *NavigationError) Error() string {
+	switch {
+	case e.StatusCode != 0:
+		return fmt.Sprintf("%s: %s: %d", e.Err, e.URL, e.StatusCode)
+	case e.Text != "":
+		return fmt.Sprintf("%s: %s: %s", e.Err, e.URL, e.Text)
+	default:
+		return fmt.Sprintf("%s: %s", e.Err, e.URL)
+	}
+}
+
+func (e *NavigationError) Unwrap() error {
+	return e.Err
+}
+
+// navigation is the load progress of the current document of a tab.
+type navigation struct {
+	id       string    // ID of the navigation reported by the protocol
+	seq      int       // incremented on each navigation
+	state    LoadState // reached by the navigation
+	err      error     // why the navigation has failed
+	requests map[string]bool
+	activity time.Time // when a request was last sent or completed
+
+	// changed is closed and replaced on each update
+	changed chan struct{}
+}
+
+// update notifies the waiters of the tab. The firefox lock must be held.
+func (t *tab) update() {
+	if t.nav.changed != nil {
+		close(t.nav.changed)
+	}
+	t.nav.changed = make(chan struct{})
+}
+
+// updateNavigation calls update with the navigation of the attached tab, and
+// notifies its waiters if update returns true.
+func (c *firefox) updateNavigation(target string, update func(nav *navigation) bool) {
+	c.Lock()
+	defer c.Unlock()
+	if t, ok := c.targets[target]; ok && update(&t.nav) {
+		t.update()
+	}
+}
+
+// navigated starts tracking the navigation of the tab, and enforces the
+// navigation policy as early as possible. Protocols may report the same
+// navigation more than once.
+func (c *firefox) navigated(target, id, url string) {
+	c.checkNavigation(target, url)
+	c.updateNavigation(target, func(nav *navigation) bool {
+		if id != "" && id == nav.id {
+			return false
+		}
+		nav.id = id
+		nav.seq++
+		nav.state = ""
+		nav.err = nil
+		nav.requests = nil
+		nav.activity = time.Now()
+		return true
+	})
+}
+
+func (c *firefox) loadStateReached(target string, state LoadState) {
+	c.updateNavigation(target, func(nav *navigation) bool {
+		if state.rank() <= nav.state.rank() {
+			return false
+		}
+		nav.state = state
+		return true
+	})
+}
+
+// request tracks the requests in flight of the tab for LoadStateNetworkIdle.
+func (c *firefox) request(target, id string, done bool) {
+	c.updateNavigation(target, func(nav *navigation) bool {
+		if nav.requests == nil {
+			nav.requests = map[string]bool{}
+		}
+		if done {
+			delete(nav.requests, id)
+		} else {
+			nav.requests[id] = true
+		}
+		nav.activity = time.Now()
+		return true
+	})
+}
+
+// navigationFailed fails the navigation of the tab, unless it has already
+// failed or was replaced.
+func (c *firefox) navigationFailed(target, id string, err *NavigationError) {
+	c.updateNavigation(target, func(nav *navigation) bool {
+		if (id != "" && nav.id != "" && id != nav.id) || nav.err != nil {
+			return false
+		}
+		nav.err = err
+		return true
+	})
+}
+
+// waitLoad waits until a navigation of the tab after the seq one reaches the
+// load state, and returns its error if it fails first.
+func (t *tab) waitLoad(ctx context.Context, seq int, until LoadState) error {
+	if until.rank() == 0 {
+		return fmt.Errorf("unknown load state %q", until)
+	}
+	c := t.firefox
+	for {
+		c.Lock()
+		if t.nav.changed == nil {
+			t.update()
+		}
+		changed, closed := t.nav.changed, t.closed
+		current, state, err := t.nav.seq, t.nav.state, t.nav.err
+		quiet := NetworkIdleTime - time.Since(t.nav.activity)
+		inFlight := len(t.nav.requests)
+		c.Unlock()
+
+		var idle <-chan time.Time
+		switch {
+		case closed:
+			return ErrTabClosed
+		case current <= seq:
+		case err != nil:
+			return err
+		case until != LoadStateNetworkIdle:
+			if state.rank() >= until.rank() {
+				return nil
+			}
+		case state == LoadStateLoad && inFlight == 0:
+			if quiet <= 0 {
+				return nil
+			}
+			idle = time.After(quiet)
+		}
+
+		select {
+		case <-changed:
+		case <-idle:
+		case <-c.done:
+			return ErrConnectionClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (t *tab) LoadAndWait(ctx context.Context, url string, until LoadState) error {
+	if err := t.checkOpen(); err != nil {
+		return err
+	}
+	t.firefox.Lock()
+	seq := t.nav.seq
+	t.firefox.Unlock()
+	if err := t.firefox.proto.Navigate(ctx, t.target, url); err != nil {
+		return err
+	}
+	return t.waitLoad(ctx, seq, until)
+}
+
+func (t *tab) WaitForNavigation(ctx context.Context, until LoadState) error {
+	if err := t.checkOpen(); err != nil {
+		return err
+	}
+	t.firefox.Lock()
+	seq := t.nav.seq
+	if seq > 0 && t.nav.err == nil && t.nav.state != LoadStateLoad {
+		// the navigation in progress counts
+		seq--
+	}
+	t.firefox.Unlock()
+	return t.waitLoad(ctx, seq, until)
+}
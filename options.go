@@ -0,0 +1,204 @@
+package gofirefox
+
+import (
+	"io/fs"
+	"log"
+	"log/slog"
+	"time"
+)
+
+// Option configures the UI created with NewWithOptions. Options override the
+// defaults taken from the GOFIREFOX_* environment variables.
+type Option func(*Config)
+
+// WithProfileDir sets the directory where the firefox profile is stored. By
+// default a new temporary directory is used, which is not removed when
+// firefox ends.
+func WithProfileDir(dir string) Option {
+	return func(c *Config) {
+		c.ProfileDir = dir
+	}
+}
+
+// WithBinary sets the path to the firefox executable.
+func WithBinary(path string) Option {
+	return func(c *Config) {
+		c.FirefoxBin = path
+	}
+}
+
+// WithLauncher sets how firefox is started, e.g. in a container or under a
+// supervisor. By default firefox is started as a child process.
+func WithLauncher(l Launcher) Option {
+	return func(c *Config) {
+		c.Launcher = l
+	}
+}
+
+// WithRestartPolicy makes firefox restarted when it crashes, is killed or its
+// window is closed, until it is closed with Close or the context passed to
+// Run is done.
+func WithRestartPolicy(p RestartPolicy) Option {
+	return func(c *Config) {
+		c.Restart = &p
+	}
+}
+
+// WithUserJSURL sets the location user.js is loaded from: http(s):// or
+// file:// URL, or a local path. An empty url disables loading user.js.
+func WithUserJSURL(url string) Option {
+	return func(c *Config) {
+		c.ProfileLocationURL = url
+	}
+}
+
+// WithProfileSource sets the source user.js is loaded from, e.g.
+// ProfileFromReader. It overrides WithUserJSURL.
+func WithProfileSource(src ProfileSource) Option {
+	return func(c *Config) {
+		c.ProfileSource = src
+	}
+}
+
+// WithUserJSSHA256 makes the downloaded user.js verified with the hex encoded
+// SHA-256 checksum.
+func WithUserJSSHA256(sum string) Option {
+	return func(c *Config) {
+		c.ProfileSHA256 = sum
+	}
+}
+
+// WithUserJSPublicKey makes user.js verified with the minisign signature
+// loaded from the user.js location with the .minisig suffix. key is
+// the base64 public key or the content of the minisign public key file.
+func WithUserJSPublicKey(key string) Option {
+	return func(c *Config) {
+		c.ProfilePublicKey = key
+	}
+}
+
+// WithCacheDir sets where the last known good user.js is cached. Empty dir
+// disables the cache.
+func WithCacheDir(dir string) Option {
+	return func(c *Config) {
+		c.CacheDir = dir
+	}
+}
+
+// WithProfileTemplate sets the files copied into the profile directory on
+// each start. Its user.js is used if the download fails or the user.js URL is
+// empty. DefaultProfileTemplate is used by default.
+func WithProfileTemplate(template fs.FS) Option {
+	return func(c *Config) {
+		c.ProfileTemplate = template
+	}
+}
+
+// WithPrefs adds user preferences to the profile user.js. Values must be bool,
+// string or integer.
+func WithPrefs(prefs map[string]interface{}) Option {
+	return func(c *Config) {
+		if c.Prefs == nil {
+			c.Prefs = map[string]interface{}{}
+		}
+		for name, value := range prefs {
+			c.Prefs[name] = value
+		}
+	}
+}
+
+// WithArgs adds extra arguments passed to the firefox executable.
+func WithArgs(args ...string) Option {
+	return func(c *Config) {
+		c.Args = append(c.Args, args...)
+	}
+}
+
+// WithKiosk enables or disables the kiosk mode. It is enabled by default.
+func WithKiosk(kiosk bool) Option {
+	return func(c *Config) {
+		c.Kiosk = kiosk
+	}
+}
+
+// WithHeadless starts firefox without a window. Kiosk mode has no effect then.
+func WithHeadless(headless bool) Option {
+	return func(c *Config) {
+		c.Headless = headless
+	}
+}
+
+// WithWindowSize sets the initial size of the firefox window.
+func WithWindowSize(width, height int) Option {
+	return func(c *Config) {
+		c.WindowWidth = width
+		c.WindowHeight = height
+	}
+}
+
+// WithLogger makes the library logs written to the logger as text lines. It
+// is ignored if WithSlog sets a logger too.
+func WithLogger(logger *log.Logger) Option {
+	return func(c *Config) {
+		c.Logger = logger
+	}
+}
+
+// WithSlog sets the structured logger used for the library logs. It takes
+// precedence over WithLogger, WithSlog(nil) makes WithLogger effective again.
+func WithSlog(logger *slog.Logger) Option {
+	return func(c *Config) {
+		c.Slog = logger
+	}
+}
+
+// WithEventBuffer sets the number of events buffered for the UI.Console and
+// UI.Exceptions channels and for each UI.On handler. Events which don't fit
+// are dropped.
+func WithEventBuffer(size int) Option {
+	return func(c *Config) {
+		c.EventBuffer = size
+	}
+}
+
+// WithProtocol sets the remote protocol firefox is driven with. Firefox is
+// deprecating CDP in favour of ProtocolBiDi.
+func WithProtocol(name ProtocolName) Option {
+	return func(c *Config) {
+		c.Protocol = name
+	}
+}
+
+// WithNavigationPolicy restricts where the tabs may navigate, e.g. to the
+// kiosk site, and how the popups are handled.
+func WithNavigationPolicy(policy NavigationPolicy) Option {
+	return func(c *Config) {
+		c.Navigation = &policy
+	}
+}
+
+// WithIdleReset resets the session once the user was inactive for the
+// timeout: the main tab is replaced with a new one without back history, the
+// storage of the open pages is cleared and the other tabs are closed, the
+// cookies and cache are cleared, and the new main tab is navigated to
+// homeURL, or to the URL the UI was created with if homeURL is empty. The
+// storage of the origins which are no longer open is kept, the remote
+// protocols cannot clear it. Resets are sent on UI.IdleResets, with
+// ErrNotSupported if the protocol cannot clear the cache, e.g. BiDi.
+//
+// The timeout only runs after user input: there is no reset without input
+// since the start or the last reset, e.g. if the next user only looked at
+// the screen, or the page navigated on its own.
+func WithIdleReset(timeout time.Duration, homeURL string) Option {
+	return func(c *Config) {
+		c.IdleTimeout = timeout
+		c.IdleHomeURL = homeURL
+	}
+}
+
+// withUserPrefs adds raw user_pref(...) lines to the profile user.js.
+func withUserPrefs(lines []string) Option {
+	return func(c *Config) {
+		c.userPrefs = append(c.userPrefs, lines...)
+	}
+}
@@ -0,0 +1,92 @@
+package gofirefox
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// PaperSize is the size of the paper in inches.
+type PaperSize struct {
+	Width  float64
+	Height float64
+}
+
+// Common paper sizes.
+var (
+	PaperLetter = PaperSize{Width: 8.5, Height: 11}
+	PaperLegal  = PaperSize{Width: 8.5, Height: 14}
+	PaperA3     = PaperSize{Width: 11.69, Height: 16.54}
+	PaperA4     = PaperSize{Width: 8.27, Height: 11.69}
+	PaperA5     = PaperSize{Width: 5.83, Height: 8.27}
+)
+
+// PDFMargins are the page margins in inches.
+type PDFMargins struct {
+	Top    float64
+	Bottom float64
+	Left   float64
+	Right  float64
+}
+
+// PDFOptions configure UI.PrintToPDF. Zero values are the browser defaults.
+type PDFOptions struct {
+	// Paper is PaperLetter by default.
+	Paper PaperSize
+	// Margins are 1cm if nil, set them to zero for a borderless page.
+	Margins *PDFMargins
+	// Landscape prints the pages in landscape orientation.
+	Landscape bool
+	// Background prints the background colors and images.
+	Background bool
+	// Scale is the scale of the page rendering, from 0.1 to 2, 1 by default.
+	Scale float64
+	// PageRanges are the pages to print, e.g. "1-5, 8, 11-13", all pages if
+	// empty.
+	PageRanges string
+	// HeaderTemplate and FooterTemplate are the HTML of the page header and
+	// footer, in which the elements with the classes date, title, url,
+	// pageNumber and totalPages are filled with the printing values. No
+	// header and footer are printed if both are empty. They are not
+	// supported with BiDi.
+	HeaderTemplate string
+	FooterTemplate string
+}
+
+// validate returns an error if the options are out of range.
+func (o PDFOptions) validate() error {
+	if o.Paper.Width < 0 || o.Paper.Height < 0 {
+		return fmt.Errorf("invalid paper size %gx%g", o.Paper.Width, o.Paper.Height)
+	}
+	if (o.Paper.Width == 0) != (o.Paper.Height == 0) {
+		return fmt.Errorf("paper size %gx%g is missing a dimension", o.Paper.Width, o.Paper.Height)
+	}
+	if m := o.Margins; m != nil && (m.Top < 0 || m.Bottom < 0 || m.Left < 0 || m.Right < 0) {
+		return fmt.Errorf("invalid margins %+v", *m)
+	}
+	if o.Scale != 0 && (o.Scale < 0.1 || o.Scale > 2) {
+		return fmt.Errorf("invalid scale %g", o.Scale)
+	}
+	return nil
+}
+
+// pageRanges returns the page ranges, e.g. ["1-5", "8"].
+func (o PDFOptions) pageRanges() []string {
+	ranges := []string{}
+	for _, r := range strings.Split(o.PageRanges, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges
+}
+
+func (t *tab) PrintToPDF(ctx context.Context, opts PDFOptions) ([]byte, error) {
+	if err := t.checkOpen(); err != nil {
+		return nil, err
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	return t.firefox.proto.PrintToPDF(ctx, t.target, opts)
+}
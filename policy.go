@@ -0,0 +1,327 @@
+package gofirefox
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// NavigationPolicy restricts where the tabs may navigate, e.g. to keep a kiosk
+// on its site. It is enforced on the navigation events of all tabs, whether
+// the navigation was started by the user, the page or the UI. If Allow or
+// Deny are set, the document requests of the tabs are paused like with
+// UI.Intercept, so blocked pages fail before they are loaded. The frames of
+// the pages are not restricted.
+type NavigationPolicy struct {
+	// Allow are the hosts, e.g. "example.com" or "*.example.com", and the URL
+	// glob patterns, e.g. "https://example.org/docs/*", the tabs may navigate
+	// to. Entries containing "://" are URL patterns, see UI.Intercept for the
+	// syntax. All URLs are allowed if Allow is empty.
+	Allow []string
+	// Deny are the hosts and URL patterns the tabs may not navigate to, even
+	// if they are allowed.
+	Deny []string
+	// OnViolation is what happens to the tab navigating to a URL which is
+	// not allowed, ViolationBlock by default.
+	OnViolation ViolationAction
+	// HomeURL is the URL of ViolationRedirectHome, the URL the UI was
+	// created with by default. The home URL and about:blank are always
+	// allowed.
+	HomeURL string
+	// Popups is how the tabs opened by the page, e.g. with window.open or
+	// target=_blank links, are handled, PopupAllow by default.
+	Popups PopupPolicy
+	// OnBlocked is called for each blocked navigation and popup, from its
+	// own goroutine.
+	OnBlocked func(BlockedNavigation)
+}
+
+// ViolationAction is what happens to a tab navigating to a URL which is not
+// allowed.
+type ViolationAction int
+
+const (
+	// ViolationBlock sends the tab back to its last allowed URL, or to the
+	// home URL if there is none. Popups without an allowed URL are closed.
+	// If the URL the tab is sent to leads to a blocked URL again, e.g. by
+	// redirecting to it, the tab is sent to the home URL and then to
+	// about:blank instead, the same for ViolationRedirectHome.
+	ViolationBlock ViolationAction = iota
+	// ViolationRedirectHome sends the tab to the home URL.
+	ViolationRedirectHome
+	// ViolationBlank opens nothing, the tab shows a blank page.
+	ViolationBlank
+)
+
+func (a ViolationAction) String() string {
+	switch a {
+	case ViolationBlock:
+		return "block"
+	case ViolationRedirectHome:
+		return "redirect home"
+	case ViolationBlank:
+		return "blank"
+	default:
+		return fmt.Sprintf("ViolationAction(%d)", int(a))
+	}
+}
+
+// PopupPolicy is how the tabs opened by the page are handled.
+type PopupPolicy int
+
+const (
+	// PopupAllow keeps the popups open as tabs, subject to the policy.
+	PopupAllow PopupPolicy = iota
+	// PopupBlock closes the popups.
+	PopupBlock
+	// PopupSameTab closes the popups and opens their URL in the tab which
+	// opened them instead.
+	PopupSameTab
+)
+
+// BlockedNavigation is a navigation or popup blocked by the NavigationPolicy.
+type BlockedNavigation struct {
+	// Tab is the ID of the tab which was navigated or opened.
+	Tab string
+	URL string
+	// Popup is true if the tab was opened by the page.
+	Popup bool
+}
+
+// urlRule matches the hosts or the URLs of a NavigationPolicy entry.
+type urlRule struct {
+	re   *regexp.Regexp
+	host bool
+}
+
+func (r urlRule) match(u *url.URL, raw string) bool {
+	if r.host {
+		return r.re.MatchString(strings.ToLower(u.Hostname()))
+	}
+	return r.re.MatchString(raw)
+}
+
+func compileRules(entries []string) ([]urlRule, error) {
+	rules := []urlRule{}
+	for _, entry := range entries {
+		host := !strings.Contains(entry, "://")
+		if host {
+			entry = strings.ToLower(entry)
+		}
+		re, err := globRegexp(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid navigation policy entry %q: %w", entry, err)
+		}
+		rules = append(rules, urlRule{re: re, host: host})
+	}
+	return rules, nil
+}
+
+// navigationGuard enforces the NavigationPolicy.
+type navigationGuard struct {
+	NavigationPolicy
+	allow, deny []urlRule
+}
+
+// newNavigationGuard returns the guard of the policy, or nil if policy is nil.
+func newNavigationGuard(policy *NavigationPolicy, home string) (*navigationGuard, error) {
+	if policy == nil {
+		return nil, nil
+	}
+	g := &navigationGuard{NavigationPolicy: *policy}
+	if g.HomeURL == "" {
+		g.HomeURL = home
+	}
+	var err error
+	if g.allow, err = compileRules(policy.Allow); err != nil {
+		return nil, err
+	}
+	if g.deny, err = compileRules(policy.Deny); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// restricts returns whether the policy blocks any URL. The guard may be nil.
+func (g *navigationGuard) restricts() bool {
+	return g != nil && (len(g.allow) > 0 || len(g.deny) > 0)
+}
+
+// allows returns whether the tabs may navigate to the URL.
+func (g *navigationGuard) allows(raw string) bool {
+	if raw == "" || raw == "about:blank" || raw == g.HomeURL {
+		return true
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	for _, r := range g.deny {
+		if r.match(u, raw) {
+			return false
+		}
+	}
+	if len(g.allow) == 0 {
+		return true
+	}
+	for _, r := range g.allow {
+		if r.match(u, raw) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkNavigation enforces the navigation policy on the tab navigating to
+// the URL, and returns whether it is allowed. The violation action is taken
+// once per blocked navigation.
+func (c *firefox) checkNavigation(target, url string) bool {
+	g := c.guard
+	if g == nil {
+		return true
+	}
+	c.Lock()
+	defer c.Unlock()
+	t, ok := c.targets[target]
+	if g.allows(url) {
+		if ok {
+			if !t.enforced[url] {
+				// navigated on its own
+				t.enforced = nil
+			}
+			t.allowedURL, t.blockedURL = url, ""
+		}
+		return true
+	}
+	if !ok || t.blockedURL == url {
+		// tabs are checked again once attached
+		return false
+	}
+	t.blockedURL = url
+	_, popup := c.popups[target]
+	to := g.violationURL(t, popup)
+	if to != "" {
+		if t.enforced == nil {
+			t.enforced = map[string]bool{}
+		}
+		t.enforced[to] = true
+	}
+	go c.enforce(target, url, to, popup)
+	return false
+}
+
+// violationURL returns the URL the tab navigating to a blocked URL is sent
+// to, or "" if the popup is closed. The URLs the tab was sent to since it
+// last navigated on its own are skipped, they have led to a blocked URL
+// again, e.g. by redirecting to it, so the tab ends up on about:blank at
+// worst.
+func (g *navigationGuard) violationURL(t *tab, popup bool) string {
+	urls := []string{}
+	switch g.OnViolation {
+	case ViolationRedirectHome:
+		urls = append(urls, g.HomeURL)
+	case ViolationBlank:
+	default:
+		if t.allowedURL != "" && !t.enforced[t.allowedURL] {
+			return t.allowedURL
+		}
+		if popup {
+			return ""
+		}
+		urls = append(urls, g.HomeURL)
+	}
+	for _, url := range urls {
+		if !t.enforced[url] {
+			return url
+		}
+	}
+	return "about:blank"
+}
+
+// enforce sends the tab navigating to the blocked URL to the URL, or closes
+// it if the URL is empty.
+func (c *firefox) enforce(target, url, to string, popup bool) {
+	g := c.guard
+	ctx := context.Background()
+	c.config.logger.Warn("navigation blocked", "target", target, "url", url, "action", g.OnViolation, "to", to)
+	var err error
+	if to == "" {
+		err = c.proto.CloseTab(ctx, target)
+	} else {
+		err = c.proto.Navigate(ctx, target, to)
+	}
+	if err != nil {
+		c.config.logger.Warn("failed to block navigation", "target", target, "url", url, "err", err)
+	}
+	if g.OnBlocked != nil {
+		g.OnBlocked(BlockedNavigation{Tab: target, URL: url, Popup: popup})
+	}
+}
+
+// tabOpened applies the popup policy to the tabs opened by the page.
+func (c *firefox) tabOpened(target, opener, url string) {
+	g := c.guard
+	if g == nil || opener == "" {
+		return
+	}
+	c.Lock()
+	c.popups[target] = opener
+	c.Unlock()
+	switch g.Popups {
+	case PopupBlock:
+		go func() {
+			c.config.logger.Warn("popup blocked", "target", target, "opener", opener, "url", url)
+			if err := c.proto.CloseTab(context.Background(), target); err != nil {
+				c.config.logger.Warn("failed to close popup", "target", target, "err", err)
+			}
+			if g.OnBlocked != nil {
+				g.OnBlocked(BlockedNavigation{Tab: target, URL: url, Popup: true})
+			}
+		}()
+	case PopupSameTab:
+		c.popupNavigated(target, url)
+	}
+}
+
+// popupChanged handles the URL change of the tab if it is a popup closed by
+// the popup policy, which must not be attached to, and returns whether it is.
+func (c *firefox) popupChanged(target, url string) bool {
+	g := c.guard
+	if g == nil || g.Popups == PopupAllow {
+		return false
+	}
+	c.Lock()
+	_, ok := c.popups[target]
+	c.Unlock()
+	if ok && g.Popups == PopupSameTab {
+		c.popupNavigated(target, url)
+	}
+	return ok
+}
+
+// popupNavigated opens the URL of the popup in its opener once it is known,
+// popups are usually opened blank and navigated.
+func (c *firefox) popupNavigated(target, url string) {
+	if url == "" || url == "about:blank" {
+		return
+	}
+	c.Lock()
+	opener := c.popups[target]
+	// the popup is being closed
+	c.popups[target] = ""
+	c.Unlock()
+	if opener == "" {
+		return
+	}
+	go func() {
+		ctx := context.Background()
+		if err := c.proto.CloseTab(ctx, target); err != nil {
+			c.config.logger.Warn("failed to close popup", "target", target, "err", err)
+		}
+		if err := c.proto.Navigate(ctx, opener, url); err != nil {
+			c.config.logger.Warn("failed to open popup in its opener", "target", opener, "url", url, "err", err)
+		}
+	}()
+}
@@ -0,0 +1,333 @@
+package prefs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// SyntaxError is returned when the preference file can't be parsed.
+type SyntaxError struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenInt
+	tokenPunct
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokenEOF:
+		return "end of file"
+	case tokenIdent:
+		return "identifier"
+	case tokenString:
+		return "string"
+	case tokenInt:
+		return "integer"
+	default:
+		return "punctuation"
+	}
+}
+
+type token struct {
+	kind tokenKind
+	// text is the identifier, punctuation or integer literal, or the decoded
+	// string value
+	text string
+	// pos is the byte offset of the token in the source
+	pos int
+}
+
+// lexer splits the preference file into tokens, skipping whitespace and
+// comments (//, # and /* */).
+type lexer struct {
+	src string
+	pos int
+}
+
+func (l *lexer) errorf(pos int, format string, args ...interface{}) error {
+	line := strings.Count(l.src[:pos], "\n") + 1
+	col := pos - strings.LastIndex(l.src[:pos], "\n")
+	return &SyntaxError{Line: line, Column: col, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (l *lexer) skip() error {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			l.pos++
+		case c == '#' || strings.HasPrefix(l.src[l.pos:], "//"):
+			end := strings.IndexByte(l.src[l.pos:], '\n')
+			if end < 0 {
+				l.pos = len(l.src)
+			} else {
+				l.pos += end + 1
+			}
+		case strings.HasPrefix(l.src[l.pos:], "/*"):
+			end := strings.Index(l.src[l.pos+2:], "*/")
+			if end < 0 {
+				return l.errorf(l.pos, "unterminated comment")
+			}
+			l.pos += end + 4
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (l *lexer) next() (token, error) {
+	if err := l.skip(); err != nil {
+		return token{}, err
+	}
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, pos: start}, nil
+	}
+
+	switch c := l.src[l.pos]; {
+	case c == '(' || c == ')' || c == ',' || c == ';':
+		l.pos++
+		return token{kind: tokenPunct, text: string(c), pos: start}, nil
+	case c == '"' || c == '\'':
+		s, err := l.string(c)
+		return token{kind: tokenString, text: s, pos: start}, err
+	case isDigit(c) || ((c == '-' || c == '+') && l.pos+1 < len(l.src) && isDigit(l.src[l.pos+1])):
+		l.pos++
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokenInt, text: l.src[start:l.pos], pos: start}, nil
+	case isIdentStart(c):
+		for l.pos < len(l.src) && (isIdentStart(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokenIdent, text: l.src[start:l.pos], pos: start}, nil
+	default:
+		r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+		return token{}, l.errorf(start, "unexpected character %q", r)
+	}
+}
+
+// string reads the string literal quoted with q. Strings may span multiple
+// lines and support the JS escape sequences.
+func (l *lexer) string(q byte) (string, error) {
+	start := l.pos
+	l.pos++
+	var b strings.Builder
+	for {
+		if l.pos >= len(l.src) {
+			return "", l.errorf(start, "unterminated string")
+		}
+		c := l.src[l.pos]
+		switch {
+		case c == q:
+			l.pos++
+			return b.String(), nil
+		case c != '\\':
+			b.WriteByte(c)
+			l.pos++
+			continue
+		}
+
+		// escape sequence
+		l.pos++
+		if l.pos >= len(l.src) {
+			return "", l.errorf(start, "unterminated string")
+		}
+		c = l.src[l.pos]
+		l.pos++
+		switch c {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'x':
+			r, err := l.hex(2)
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(r)
+		case 'u':
+			r, err := l.hex(4)
+			if err != nil {
+				return "", err
+			}
+			if utf16.IsSurrogate(r) && strings.HasPrefix(l.src[l.pos:], "\\u") {
+				l.pos += 2
+				r2, err := l.hex(4)
+				if err != nil {
+					return "", err
+				}
+				r = utf16.DecodeRune(r, r2)
+			}
+			b.WriteRune(r)
+		default:
+			// \", \', \\ and unknown escapes stand for the character itself
+			b.WriteByte(c)
+		}
+	}
+}
+
+func (l *lexer) hex(n int) (rune, error) {
+	if l.pos+n > len(l.src) {
+		return 0, l.errorf(l.pos, "invalid escape sequence")
+	}
+	v, err := strconv.ParseUint(l.src[l.pos:l.pos+n], 16, 32)
+	if err != nil {
+		return 0, l.errorf(l.pos, "invalid escape sequence")
+	}
+	l.pos += n
+	return rune(v), nil
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// prefFuncs are the functions setting preferences in user.js, prefs.js and
+// autoconfig files.
+var prefFuncs = map[string]bool{
+	"pref":        true,
+	"user_pref":   true,
+	"sticky_pref": true,
+	"lockPref":    true,
+	"defaultPref": true,
+}
+
+// Parse parses the preference file, e.g. user.js or prefs.js.
+func Parse(data []byte) (*File, error) {
+	l := &lexer{src: string(data)}
+	f := &File{}
+	last := 0
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		if tok.pos > last {
+			f.nodes = append(f.nodes, node{raw: l.src[last:tok.pos]})
+		}
+		if tok.kind == tokenEOF {
+			return f, nil
+		}
+		n, err := parseStatement(l, tok)
+		if err != nil {
+			return nil, err
+		}
+		n.raw = l.src[tok.pos:l.pos]
+		if n.fn == "lockPref" || n.fn == "defaultPref" {
+			f.Autoconfig = true
+		}
+		f.nodes = append(f.nodes, n)
+		last = l.pos
+	}
+}
+
+// parseStatement parses fn("name", value[, attribute]...); where fn is the
+// already read token.
+func parseStatement(l *lexer, fn token) (node, error) {
+	n := node{fn: fn.text}
+	if fn.kind != tokenIdent || !prefFuncs[fn.text] {
+		return n, l.errorf(fn.pos, "expected pref function, got %s %q", fn.kind, fn.text)
+	}
+	if _, err := expect(l, tokenPunct, "("); err != nil {
+		return n, err
+	}
+	name, err := expect(l, tokenString, "")
+	if err != nil {
+		return n, err
+	}
+	n.pref.Name = name.text
+	if _, err := expect(l, tokenPunct, ","); err != nil {
+		return n, err
+	}
+
+	tok, err := l.next()
+	if err != nil {
+		return n, err
+	}
+	switch {
+	case tok.kind == tokenString:
+		n.pref.Value = tok.text
+	case tok.kind == tokenInt:
+		v, err := strconv.ParseInt(tok.text, 10, 32)
+		if err != nil {
+			return n, l.errorf(tok.pos, "integer %s out of range", tok.text)
+		}
+		n.pref.Value = int(v)
+	case tok.kind == tokenIdent && (tok.text == "true" || tok.text == "false"):
+		n.pref.Value = tok.text == "true"
+	default:
+		return n, l.errorf(tok.pos, "expected pref value, got %s %q", tok.kind, tok.text)
+	}
+
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return n, err
+		}
+		if tok.kind == tokenPunct && tok.text == ")" {
+			break
+		}
+		if tok.kind != tokenPunct || tok.text != "," {
+			return n, l.errorf(tok.pos, "expected \",\" or \")\", got %s %q", tok.kind, tok.text)
+		}
+		attr, err := expect(l, tokenIdent, "")
+		if err != nil {
+			return n, err
+		}
+		switch attr.text {
+		case "sticky":
+			n.sticky = true
+		case "locked":
+			n.pref.Locked = true
+		default:
+			return n, l.errorf(attr.pos, "unknown pref attribute %q", attr.text)
+		}
+	}
+	if _, err := expect(l, tokenPunct, ";"); err != nil {
+		return n, err
+	}
+
+	if fn.text == "lockPref" {
+		n.pref.Locked = true
+	}
+	return n, nil
+}
+
+// expect reads the next token, which must be of the given kind and, if text
+// is not empty, have the given text.
+func expect(l *lexer, kind tokenKind, text string) (token, error) {
+	tok, err := l.next()
+	if err != nil {
+		return tok, err
+	}
+	if tok.kind != kind || (text != "" && tok.text != text) {
+		want := kind.String()
+		if text != "" {
+			want = strconv.Quote(text)
+		}
+		return tok, l.errorf(tok.pos, "expected %s, got %s %q", want, tok.kind, tok.text)
+	}
+	return tok, nil
+}
@@ -0,0 +1,311 @@
+// Package prefs reads and writes firefox preference files, such as user.js and
+// prefs.js.
+//
+// Files are parsed into a list of statements and the text between them, so
+// comments, ordering and formatting of the statements which are not modified
+// are preserved when the file is written back.
+package prefs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Pref is a single firefox preference.
+type Pref struct {
+	Name string
+	// Value is bool, int or string
+	Value interface{}
+	// Locked is set for preferences which can't be changed by the user, e.g.
+	// set with lockPref.
+	Locked bool
+}
+
+// String returns the preference as a user.js statement.
+func (p Pref) String() string {
+	n := node{fn: "user_pref", pref: p}
+	return n.statement(false)
+}
+
+// New returns the preference with the value converted to one of the
+// supported types. Values must be bool, string or integer, integers must fit
+// into 32 bits like in the preference files.
+func New(name string, value interface{}) (Pref, error) {
+	p := Pref{Name: name}
+	var i int64
+	switch v := value.(type) {
+	case bool, string:
+		p.Value = v
+		return p, nil
+	case int:
+		i = int64(v)
+	case int8:
+		i = int64(v)
+	case int16:
+		i = int64(v)
+	case int32:
+		i = int64(v)
+	case int64:
+		i = v
+	case uint8:
+		i = int64(v)
+	case uint16:
+		i = int64(v)
+	case uint32:
+		i = int64(v)
+	case uint:
+		if v > math.MaxInt32 {
+			return p, fmt.Errorf("integer %d of preference %s out of range", v, name)
+		}
+		i = int64(v)
+	case uint64:
+		if v > math.MaxInt32 {
+			return p, fmt.Errorf("integer %d of preference %s out of range", v, name)
+		}
+		i = int64(v)
+	default:
+		return p, fmt.Errorf("unsupported type %T of preference %s", value, name)
+	}
+	if i < math.MinInt32 || i > math.MaxInt32 {
+		return p, fmt.Errorf("integer %d of preference %s out of range", i, name)
+	}
+	p.Value = int(i)
+	return p, nil
+}
+
+// FromMap returns preferences from the name to value map, sorted by name.
+func FromMap(m map[string]interface{}) ([]Pref, error) {
+	prefs := make([]Pref, 0, len(m))
+	for name, value := range m {
+		p, err := New(name, value)
+		if err != nil {
+			return nil, err
+		}
+		prefs = append(prefs, p)
+	}
+	sort.Slice(prefs, func(i, j int) bool { return prefs[i].Name < prefs[j].Name })
+	return prefs, nil
+}
+
+// node is either a pref statement, or the text between statements if fn is
+// empty.
+type node struct {
+	// raw is the original text, empty if the statement was added or changed
+	raw      string
+	fn       string
+	sticky   bool
+	pref     Pref
+	appended bool
+}
+
+// statement formats the node as a statement, keeping its pref function.
+// Locked preferences are written with lockPref in autoconfig files, which
+// only have the pref, defaultPref and lockPref functions, and with the locked
+// attribute of pref in the other files, where lockPref doesn't exist.
+func (n *node) statement(autoconfig bool) string {
+	fn, attrs := n.fn, ""
+	if autoconfig {
+		switch {
+		case n.pref.Locked:
+			fn = "lockPref"
+		case fn != "defaultPref":
+			fn = "pref"
+		}
+		return fmt.Sprintf("%s(%s, %s);", fn, quote(n.pref.Name), formatValue(n.pref.Value))
+	}
+	sticky := n.sticky || fn == "sticky_pref"
+	switch {
+	case n.pref.Locked:
+		fn = "pref"
+		attrs += ", locked"
+	case fn == "lockPref" || fn == "defaultPref":
+		fn = "user_pref"
+	}
+	if sticky && fn == "pref" {
+		attrs += ", sticky"
+	}
+	return fmt.Sprintf("%s(%s, %s%s);", fn, quote(n.pref.Name), formatValue(n.pref.Value), attrs)
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return quote(v)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// quote returns s as a double quoted JS string literal.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// File is a parsed preference file.
+type File struct {
+	// Autoconfig is set for autoconfig files, e.g. mozilla.cfg, which changes
+	// how added and changed statements are written. Parse sets it if the file
+	// uses lockPref or defaultPref, which only exist in autoconfig files.
+	Autoconfig bool
+
+	nodes []node
+}
+
+// ReadFile reads and parses the preference file.
+func ReadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Prefs returns all preferences in the order they are set in the file. A
+// preference set multiple times is returned multiple times.
+func (f *File) Prefs() []Pref {
+	var prefs []Pref
+	for _, n := range f.nodes {
+		if n.fn != "" {
+			prefs = append(prefs, n.pref)
+		}
+	}
+	return prefs
+}
+
+// Get returns the effective value of the preference, which is the last one
+// set in the file.
+func (f *File) Get(name string) (Pref, bool) {
+	for i := len(f.nodes) - 1; i >= 0; i-- {
+		if n := f.nodes[i]; n.fn != "" && n.pref.Name == name {
+			return n.pref, true
+		}
+	}
+	return Pref{}, false
+}
+
+// Set changes all statements setting the preference in place, or appends a
+// new statement if the preference is not set in the file.
+func (f *File) Set(p Pref) error {
+	v, err := New(p.Name, p.Value)
+	if err != nil {
+		return err
+	}
+	v.Locked = p.Locked
+	p = v
+	found := false
+	for i := range f.nodes {
+		n := &f.nodes[i]
+		if n.fn == "" || n.pref.Name != p.Name {
+			continue
+		}
+		found = true
+		if n.pref != p {
+			n.pref = p
+			n.raw = ""
+		}
+	}
+	if !found {
+		f.nodes = append(f.nodes, node{fn: "user_pref", pref: p, appended: true})
+	}
+	return nil
+}
+
+// Merge sets all overrides in the file. If an override is given multiple
+// times the last one wins. Overrides are applied in the order of their names,
+// so the result doesn't depend on the order they are given in.
+func (f *File) Merge(overrides ...Pref) error {
+	byName := map[string]Pref{}
+	for _, p := range overrides {
+		byName[p.Name] = p
+	}
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := f.Set(byName[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes all statements setting the preference.
+func (f *File) Delete(name string) {
+	nodes := f.nodes[:0]
+	for i, n := range f.nodes {
+		if n.fn != "" && n.pref.Name == name {
+			// drop the line break following the statement too
+			if i+1 < len(f.nodes) && f.nodes[i+1].fn == "" {
+				f.nodes[i+1].raw = strings.TrimPrefix(strings.TrimPrefix(f.nodes[i+1].raw, "\r"), "\n")
+			}
+			continue
+		}
+		nodes = append(nodes, n)
+	}
+	f.nodes = nodes
+}
+
+// Bytes returns the file content. Statements which were not changed are kept
+// byte for byte, new statements are appended one per line.
+func (f *File) Bytes() []byte {
+	var b bytes.Buffer
+	for _, n := range f.nodes {
+		if n.appended && b.Len() > 0 && !bytes.HasSuffix(b.Bytes(), []byte("\n")) {
+			b.WriteByte('\n')
+		}
+		if n.raw != "" {
+			b.WriteString(n.raw)
+		} else if n.fn != "" {
+			b.WriteString(n.statement(f.Autoconfig))
+		}
+		if n.appended {
+			b.WriteByte('\n')
+		}
+	}
+	return b.Bytes()
+}
+
+// WriteTo writes the file content to w.
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(f.Bytes())
+	return int64(n), err
+}
+
+// WriteFile writes the file content to the named file.
+func (f *File) WriteFile(path string, perm os.FileMode) error {
+	return os.WriteFile(path, f.Bytes(), perm)
+}
@@ -0,0 +1,231 @@
+package prefs
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		src  string
+		want []Pref
+	}{
+		{
+			name: "user_pref",
+			src:  `user_pref("browser.startup.page", 1);`,
+			want: []Pref{{Name: "browser.startup.page", Value: 1}},
+		},
+		{
+			name: "all value types",
+			src:  "user_pref(\"a\", true);\nuser_pref(\"b\", false);\nuser_pref(\"c\", -42);\nuser_pref(\"d\", 'text');\n",
+			want: []Pref{{Name: "a", Value: true}, {Name: "b", Value: false}, {Name: "c", Value: -42}, {Name: "d", Value: "text"}},
+		},
+		{
+			name: "string with comma and parenthesis",
+			src:  `user_pref("intl.accept_languages", "en-US, en);");`,
+			want: []Pref{{Name: "intl.accept_languages", Value: "en-US, en);"}},
+		},
+		{
+			name: "escapes",
+			src:  `user_pref("a", "q\"uo\\te\n\x41é😀");`,
+			want: []Pref{{Name: "a", Value: "q\"uo\\te\nAé😀"}},
+		},
+		{
+			name: "comments",
+			src: `// line comment user_pref("x", 1);
+# hash comment
+/* block
+   user_pref("y", 2);
+*/
+user_pref("a", /* inline */ 1); // trailing`,
+			want: []Pref{{Name: "a", Value: 1}},
+		},
+		{
+			name: "pref functions",
+			src:  `pref("a", 1); sticky_pref("b", 2); lockPref("c", 3); defaultPref("d", 4); pref("e", 5, locked); pref("f", 6, sticky);`,
+			want: []Pref{
+				{Name: "a", Value: 1},
+				{Name: "b", Value: 2},
+				{Name: "c", Value: 3, Locked: true},
+				{Name: "d", Value: 4},
+				{Name: "e", Value: 5, Locked: true},
+				{Name: "f", Value: 6},
+			},
+		},
+		{
+			name: "multi-line",
+			src:  "user_pref(\n  \"a\",\n  \"first\nsecond\"\n);",
+			want: []Pref{{Name: "a", Value: "first\nsecond"}},
+		},
+		{
+			name: "empty",
+			src:  "\n// nothing here\n",
+			want: nil,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse([]byte(test.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Prefs(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %#v, want %#v", got, test.want)
+			}
+			if got := string(f.Bytes()); got != test.src {
+				t.Errorf("round trip: got %q, want %q", got, test.src)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"unterminated string", `user_pref("a", "b);`, `line 1, column 16: unterminated string`},
+		{"missing semicolon", "user_pref(\"a\", 1)\nuser_pref(\"b\", 2);", `line 2, column 1: expected ";", got identifier "user_pref"`},
+		{"unknown function", `set_pref("a", 1);`, `line 1, column 1: expected pref function, got identifier "set_pref"`},
+		{"bad value", `user_pref("a", null);`, `line 1, column 16: expected pref value, got identifier "null"`},
+		{"unknown attribute", `pref("a", 1, frozen);`, `line 1, column 14: unknown pref attribute "frozen"`},
+		{"unterminated comment", `/* user_pref("a", 1);`, `line 1, column 1: unterminated comment`},
+		{"integer out of range", `user_pref("a", 4294967296);`, `line 1, column 16: integer 4294967296 out of range`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse([]byte(test.src))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if err.Error() != test.want {
+				t.Errorf("got %q, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		value interface{}
+		want  interface{}
+		err   string
+	}{
+		{"bool", true, true, ""},
+		{"string", "text", "text", ""},
+		{"int", 42, 42, ""},
+		{"int64", int64(-42), -42, ""},
+		{"uint16", uint16(42), 42, ""},
+		{"max int32", int64(math.MaxInt32), math.MaxInt32, ""},
+		{"min int32", int64(math.MinInt32), math.MinInt32, ""},
+		{"int out of range", math.MaxInt32 + 1, nil, "integer 2147483648 of preference a out of range"},
+		{"int64 out of range", int64(math.MinInt32 - 1), nil, "integer -2147483649 of preference a out of range"},
+		{"uint64 out of range", uint64(math.MaxUint64), nil, "integer 18446744073709551615 of preference a out of range"},
+		{"float", 1.5, nil, "unsupported type float64 of preference a"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := New("a", test.value)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Value != test.want {
+				t.Errorf("got %#v, want %#v", p.Value, test.want)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		src       string
+		overrides []Pref
+		want      string
+	}{
+		{
+			name:      "update in place",
+			src:       "// start\nuser_pref(\"a\", 1); // keep\nuser_pref(\"b\", 2);\n",
+			overrides: []Pref{{Name: "a", Value: "x, y"}},
+			want:      "// start\nuser_pref(\"a\", \"x, y\"); // keep\nuser_pref(\"b\", 2);\n",
+		},
+		{
+			name:      "append sorted",
+			src:       "user_pref(\"m\", 1);",
+			overrides: []Pref{{Name: "z", Value: true}, {Name: "a", Value: false}, {Name: "m", Value: 1}},
+			want:      "user_pref(\"m\", 1);\nuser_pref(\"a\", false);\nuser_pref(\"z\", true);\n",
+		},
+		{
+			name:      "last override wins",
+			src:       "",
+			overrides: []Pref{{Name: "a", Value: 1}, {Name: "a", Value: 2}},
+			want:      "user_pref(\"a\", 2);\n",
+		},
+		{
+			name:      "keeps pref function",
+			src:       "pref(\"a\", 1, sticky);\nsticky_pref(\"b\", 1);\n",
+			overrides: []Pref{{Name: "a", Value: 2, Locked: true}, {Name: "b", Value: 2}},
+			want:      "pref(\"a\", 2, locked, sticky);\nsticky_pref(\"b\", 2);\n",
+		},
+		{
+			name:      "locks with pref attribute",
+			src:       "user_pref(\"a\", 1);\n",
+			overrides: []Pref{{Name: "a", Value: 2, Locked: true}, {Name: "b", Value: true, Locked: true}},
+			want:      "pref(\"a\", 2, locked);\npref(\"b\", true, locked);\n",
+		},
+		{
+			name:      "autoconfig",
+			src:       "// mozilla.cfg\nlockPref(\"a\", 1);\npref(\"b\", 1);\n",
+			overrides: []Pref{{Name: "a", Value: 2}, {Name: "b", Value: 2, Locked: true}, {Name: "c", Value: true, Locked: true}, {Name: "d", Value: "x"}},
+			want:      "// mozilla.cfg\npref(\"a\", 2);\nlockPref(\"b\", 2);\nlockPref(\"c\", true);\npref(\"d\", \"x\");\n",
+		},
+		{
+			name:      "duplicates",
+			src:       "user_pref(\"a\", 1);\nuser_pref(\"a\", 2);\n",
+			overrides: []Pref{{Name: "a", Value: 3}},
+			want:      "user_pref(\"a\", 3);\nuser_pref(\"a\", 3);\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse([]byte(test.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Merge(test.overrides...); err != nil {
+				t.Fatal(err)
+			}
+			if got := string(f.Bytes()); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+			// the result must parse back to the same preferences
+			g, err := Parse(f.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(g.Prefs(), f.Prefs()) {
+				t.Errorf("reparsed %#v, want %#v", g.Prefs(), f.Prefs())
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	f, err := Parse([]byte("user_pref(\"a\", 1);\n// b\nuser_pref(\"b\", 2);\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Delete("b")
+	if got, want := string(f.Bytes()), "user_pref(\"a\", 1);\n// b\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if _, ok := f.Get("b"); ok {
+		t.Error("b is still set")
+	}
+}
@@ -0,0 +1,59 @@
+package gofirefox
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+//go:embed profile
+var embeddedProfile embed.FS
+
+// DefaultProfileTemplate is the baseline profile shipped with the library. It
+// contains user.js used when the remote user.js can't be downloaded.
+var DefaultProfileTemplate fs.FS = mustSub(embeddedProfile, "profile")
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+// profileFallbackTimeout is how long user.js is loaded for when the profile
+// template provides one to fall back to.
+var profileFallbackTimeout = 5 * time.Second
+
+// hasUserJS returns whether the profile template provides user.js.
+func hasUserJS(template fs.FS) bool {
+	if template == nil {
+		return false
+	}
+	_, err := fs.Stat(template, "user.js")
+	return err == nil
+}
+
+// copyProfileTemplate copies all files from the template into the profile
+// directory, overwriting existing ones.
+func copyProfileTemplate(template fs.FS, profileDir string) error {
+	if template == nil {
+		return nil
+	}
+	return fs.WalkDir(template, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(profileDir, filepath.FromSlash(path))
+		if d.IsDir() {
+			return os.MkdirAll(target, 0700)
+		}
+		data, err := fs.ReadFile(template, path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0644)
+	})
+}
@@ -0,0 +1,47 @@
+// go-firefox baseline profile.
+// It is copied into every profile and used as user.js when the remote user.js
+// can't be downloaded, so startup works offline.
+
+// Skip the first run, welcome and default browser pages
+user_pref("browser.shell.checkDefaultBrowser", false);
+user_pref("browser.startup.homepage_override.mstone", "ignore");
+user_pref("startup.homepage_welcome_url", "");
+user_pref("startup.homepage_welcome_url.additional", "");
+user_pref("browser.aboutwelcome.enabled", false);
+user_pref("trailhead.firstrun.didSeeAboutWelcome", true);
+user_pref("datareporting.policy.dataSubmissionPolicyBypassNotification", true);
+user_pref("toolkit.telemetry.reportingpolicy.firstRun", false);
+
+// Don't offer to restore the previous session after a crash
+user_pref("browser.sessionstore.resume_from_crash", false);
+user_pref("browser.sessionstore.max_resumed_crashes", 0);
+user_pref("toolkit.startup.max_resumed_crashes", -1);
+
+// Updates are managed outside of the kiosk
+user_pref("app.update.auto", false);
+user_pref("app.update.checkInstallTime", false);
+user_pref("extensions.update.enabled", false);
+
+// Telemetry, studies and reporting
+user_pref("datareporting.healthreport.uploadEnabled", false);
+user_pref("datareporting.policy.dataSubmissionEnabled", false);
+user_pref("toolkit.telemetry.enabled", false);
+user_pref("toolkit.telemetry.unified", false);
+user_pref("app.normandy.enabled", false);
+user_pref("app.shield.optoutstudies.enabled", false);
+user_pref("browser.crashReports.unsubmittedCheck.autoSubmit2", false);
+
+// Prompts and popups which get in the way of a kiosk
+user_pref("browser.tabs.warnOnClose", false);
+user_pref("browser.warnOnQuit", false);
+user_pref("browser.translations.automaticallyPopup", false);
+user_pref("extensions.pocket.enabled", false);
+user_pref("browser.discovery.enabled", false);
+user_pref("signon.rememberSignons", false);
+user_pref("browser.formfill.enable", false);
+user_pref("browser.newtabpage.activity-stream.feeds.section.topstories", false);
+
+// Remote debugging, required by go-firefox
+user_pref("devtools.chrome.enabled", true);
+user_pref("devtools.debugger.prompt-connection", false);
+user_pref("devtools.debugger.remote-enabled", true);
@@ -0,0 +1,77 @@
+package gofirefox
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestCopyProfileTemplate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "user.js"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	template := fstest.MapFS{
+		"user.js":               {Data: []byte(`user_pref("a", 1);`)},
+		"chrome/userChrome.css": {Data: []byte("#nav-bar { display: none; }")},
+	}
+	if err := copyProfileTemplate(template, dir); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{
+		"user.js":               `user_pref("a", 1);`,
+		"chrome/userChrome.css": "#nav-bar { display: none; }",
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(path)))
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v, want %q", path, data, err, want)
+		}
+	}
+
+	if err := copyProfileTemplate(nil, t.TempDir()); err != nil {
+		t.Errorf("copyProfileTemplate(nil) = %v", err)
+	}
+}
+
+func TestOfflineProfileFallback(t *testing.T) {
+	timeout := profileFallbackTimeout
+	profileFallbackTimeout = 200 * time.Millisecond
+	defer func() { profileFallbackTimeout = timeout }()
+
+	// an unreachable server, connections are refused
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	dir := t.TempDir()
+	c := &firefox{
+		config: Config{
+			ProfileDir:      dir,
+			ProfileTemplate: fstest.MapFS{"user.js": {Data: []byte(`user_pref("template", true);`)}},
+			logger:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		profileSource: ProfileFromURL(srv.URL + "/user.js"),
+		proto:         newCDP(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	}
+	started := time.Now()
+	if err := c.bootstrapFirefoxProfile(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(started); elapsed > 2*time.Second {
+		t.Errorf("fell back to the template after %s", elapsed)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "user.js"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `user_pref("template", true);`) {
+		t.Errorf("user.js = %s, want the template", data)
+	}
+}
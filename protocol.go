@@ -0,0 +1,364 @@
+package gofirefox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/unikiosk/go-firefox/prefs"
+	"golang.org/x/net/websocket"
+)
+
+// ProtocolName selects the remote protocol firefox is driven with.
+type ProtocolName string
+
+const (
+	// ProtocolCDP is the partial Chrome DevTools Protocol support of firefox,
+	// which is being deprecated.
+	ProtocolCDP ProtocolName = "cdp"
+	// ProtocolBiDi is WebDriver BiDi.
+	ProtocolBiDi ProtocolName = "bidi"
+)
+
+// Protocol drives firefox over its remote protocol. Tabs are identified by the
+// target ID (CDP) or the browsing context ID (BiDi), JS realms by the
+// execution context ID or the realm ID.
+type Protocol interface {
+	// Prefs returns the preferences which enable the protocol in firefox.
+	Prefs() []prefs.Pref
+	// Endpoint matches the firefox stderr line announcing the websocket URL
+	// of the protocol, the URL is its first group.
+	Endpoint() *regexp.Regexp
+	// Connect connects to the announced websocket URL and returns the ID of
+	// the initial tab. Events are passed to events from a single goroutine
+	// until the connection is closed.
+	Connect(ctx context.Context, url string, events ProtocolEvents) (string, error)
+	// Close asks the browser to close, without waiting for it.
+	Close() error
+	// Disconnect closes the connection. Pending and further commands fail
+	// with ErrConnectionClosed.
+	Disconnect() error
+
+	// Attach prepares the tab for commands and events.
+	Attach(ctx context.Context, tab string) error
+	// NewTab opens a new tab with the url and returns its ID.
+	NewTab(ctx context.Context, url string) (string, error)
+	ActivateTab(ctx context.Context, tab string) error
+	CloseTab(ctx context.Context, tab string) error
+	// Navigate starts loading the url in the tab, without waiting for it.
+	// Navigations failing right away are returned as *NavigationError.
+	Navigate(ctx context.Context, tab, url string) error
+	// Evaluate evaluates the JS expression in the realm of the tab, or in
+	// its current document if realm is empty. Promises are awaited, and JS
+	// exceptions are returned as *JSException.
+	Evaluate(ctx context.Context, tab, realm, expr string) (Value, error)
+	// AddScript makes the JS source evaluated in each document loaded in the
+	// tab later.
+	AddScript(ctx context.Context, tab, source string) error
+	// Intercept pauses the requests of the tab whose URL matches any of the
+	// glob patterns, see UI.Intercept, and the document requests of its
+	// navigations if navigations is set, or stops pausing them if there are
+	// none. Paused requests are passed to ProtocolEvents.RequestPaused.
+	Intercept(ctx context.Context, tab string, patterns []string, navigations bool) error
+	// ContinueRequest sends the paused request, with the headers instead of
+	// its own if not nil.
+	ContinueRequest(ctx context.Context, tab, request string, headers map[string]string) error
+	// FulfillRequest responds to the paused request without sending it.
+	FulfillRequest(ctx context.Context, tab, request string, status int, headers map[string]string, body []byte) error
+	// FailRequest fails the paused request with a network error.
+	FailRequest(ctx context.Context, tab, request string) error
+	// Screenshot captures the viewport of the tab, the whole page if fullPage,
+	// or the clip relative to the document if not nil. quality is the JPEG
+	// quality from 1 to 100, the browser default if zero.
+	Screenshot(ctx context.Context, tab string, format ImageFormat, quality int, fullPage bool, clip *Rect) ([]byte, error)
+	// PrintToPDF prints the document of the tab to PDF.
+	PrintToPDF(ctx context.Context, tab string, opts PDFOptions) ([]byte, error)
+	// ClearBrowsingData clears the cookies and the HTTP cache of the browser.
+	// What the protocol cannot clear is reported as ErrNotSupported, after
+	// clearing the rest. The remote protocols of firefox cannot clear the
+	// storage of the origins, e.g. localStorage and IndexedDB.
+	ClearBrowsingData(ctx context.Context, tab string) error
+	// Send sends the raw command to the tab, or to the browser if tab is
+	// empty, and returns its raw result. Errors returned by the browser are
+	// *ProtocolError.
+	Send(ctx context.Context, tab, method string, params interface{}) (json.RawMessage, error)
+}
+
+// ErrNotSupported is returned for the features the protocol lacks.
+var ErrNotSupported = errors.New("gofirefox: not supported by the protocol")
+
+// ProtocolError is the error returned by the browser for a command.
+type ProtocolError struct {
+	// Code is the JSON-RPC error code of CDP, zero for BiDi.
+	Code    int
+	Message string
+	// Data is the additional information about the error, if any.
+	Data string
+}
+
+func (e *ProtocolError) Error() string {
+	if e.Data != "" {
+		return e.Message + ": " + e.Data
+	}
+	return e.Message
+}
+
+// ProtocolEvents are the browser events passed from the Protocol. Handlers
+// must not block.
+type ProtocolEvents struct {
+	// TabOpened is called when a tab is opened, with the ID of the tab which
+	// opened it if it was opened by the page, e.g. a popup. TabChanged is
+	// called next.
+	TabOpened func(tab, opener, url string)
+	// TabChanged is called when a tab is opened, e.g. a popup, or navigated.
+	TabChanged func(tab, url string)
+	// TabClosed is called when a tab is closed.
+	TabClosed func(tab string)
+	// RealmCreated is called when a new JS realm is created in the tab, e.g.
+	// on navigation.
+	RealmCreated func(tab, realm string)
+	// Console is called for messages logged to the JS console.
+	Console func(tab, realm string, msg ConsoleMessage)
+	// Exception is called for JS exceptions which weren't caught.
+	Exception func(tab string, err *JSException)
+	// NavigationStarted is called when a new document starts loading in the
+	// tab, with the ID of the navigation and its URL. It may be called more
+	// than once for the same navigation, e.g. on redirects.
+	NavigationStarted func(tab, navigation, url string)
+	// LoadStateReached is called when the document of the tab reaches
+	// LoadStateDOMContentLoaded and LoadStateLoad.
+	LoadStateReached func(tab string, state LoadState)
+	// NavigationFailed is called when the navigation of the tab fails, with
+	// its ID if known.
+	NavigationFailed func(tab, navigation string, err *NavigationError)
+	// Request is called when a request of the tab is sent, and when it has
+	// completed or failed.
+	Request func(tab, request string, done bool)
+	// RequestPaused is called for the requests paused by Intercept, which
+	// must be continued, fulfilled or failed.
+	RequestPaused func(tab string, r *InterceptedRequest)
+	// Event is called for every event with its raw params, and the tab it
+	// was sent from or "" for browser events.
+	Event func(tab, method string, params json.RawMessage)
+	// Disconnected is called when the connection is closed.
+	Disconnected func()
+}
+
+// ConsoleMessage is a message logged to the JS console.
+type ConsoleMessage struct {
+	// Type is the console method, e.g. "log", "debug" or "error".
+	Type string
+	// Text is the message as shown in the console.
+	Text string
+	Args []Value
+	// Tab is the ID of the tab the message was logged in.
+	Tab string
+	// URL, LineNumber and ColumnNumber locate the console call, if known.
+	// Line and column numbers are zero-based.
+	URL          string
+	LineNumber   int
+	ColumnNumber int
+	// Timestamp is when the message was logged.
+	Timestamp time.Time
+}
+
+// Level returns the log level matching the console method.
+func (m ConsoleMessage) Level() slog.Level {
+	switch m.Type {
+	case "error", "assert":
+		return slog.LevelError
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "debug", "trace":
+		return slog.LevelDebug
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// setCallFrames locates the message at the top frame of its stack trace.
+func (m *ConsoleMessage) setCallFrames(frames []CallFrame) {
+	if len(frames) > 0 {
+		m.URL, m.LineNumber, m.ColumnNumber = frames[0].URL, frames[0].LineNumber, frames[0].ColumnNumber
+	}
+}
+
+// consoleText returns the console arguments as shown in the console: strings
+// as they are, other values JSON encoded.
+func consoleText(args []Value) string {
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		s := ""
+		switch {
+		case arg.IsUndefined():
+			s = "undefined"
+		case arg.To(&s) != nil:
+			s = string(arg.Bytes())
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " ")
+}
+
+// epochMillis returns the time of the protocol timestamp in milliseconds since
+// the epoch, or the zero time if it is not set.
+func epochMillis(ms float64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMicro(int64(ms * 1000))
+}
+
+func (m ConsoleMessage) String() string {
+	s := "console." + m.Type + ":"
+	for _, arg := range m.Args {
+		s += " " + string(arg.Bytes())
+	}
+	return s
+}
+
+// newProtocol returns the named protocol.
+func newProtocol(name ProtocolName, logger *slog.Logger) (Protocol, error) {
+	switch name {
+	case ProtocolCDP, "":
+		return newCDP(logger), nil
+	case ProtocolBiDi:
+		return newBiDi(logger), nil
+	default:
+		return nil, fmt.Errorf("unknown protocol %q", name)
+	}
+}
+
+// logCommand logs the command at debug level once it has completed.
+func logCommand(logger *slog.Logger, method string, started time.Time, err error, attrs ...any) {
+	if !logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+	attrs = append(attrs, "method", method, "duration", time.Since(started))
+	if err != nil {
+		attrs = append(attrs, "err", err)
+	}
+	logger.Debug("command completed", attrs...)
+}
+
+// conn is the websocket connection tracking the commands sent until their
+// results arrive.
+type conn struct {
+	id int32
+
+	sync.Mutex
+	ws      *websocket.Conn
+	pending map[int]chan result
+	closed  bool
+}
+
+func newConn(firstID int32) *conn {
+	return &conn{id: firstID, pending: map[int]chan result{}}
+}
+
+func (c *conn) dial(url string) error {
+	ws, err := websocket.Dial(url, "", "http://127.0.0.1")
+	if err != nil {
+		return err
+	}
+	c.Lock()
+	c.ws = ws
+	c.Unlock()
+	return nil
+}
+
+// next returns the next command ID.
+func (c *conn) next() int {
+	return int(atomic.AddInt32(&c.id, 1))
+}
+
+// send sends the message without waiting for a result.
+func (c *conn) send(v interface{}) error {
+	c.Lock()
+	ws, closed := c.ws, c.closed
+	c.Unlock()
+	if ws == nil || closed {
+		return ErrConnectionClosed
+	}
+	if err := websocket.JSON.Send(ws, v); err != nil {
+		return fmt.Errorf("%w: %s", ErrConnectionClosed, err)
+	}
+	return nil
+}
+
+// receive reads the next message.
+func (c *conn) receive(v interface{}) error {
+	c.Lock()
+	ws := c.ws
+	c.Unlock()
+	return websocket.JSON.Receive(ws, v)
+}
+
+// call sends the message carrying the command id and waits for its result. It
+// returns ErrConnectionClosed if the connection is lost before the result
+// arrives, or the context error if ctx is done first.
+func (c *conn) call(ctx context.Context, id int, v interface{}) (json.RawMessage, error) {
+	// buffered, so the read loop never blocks on a caller which gave up waiting
+	resc := make(chan result, 1)
+	c.Lock()
+	if c.ws == nil || c.closed {
+		c.Unlock()
+		return nil, ErrConnectionClosed
+	}
+	c.pending[id] = resc
+	c.Unlock()
+
+	if err := c.send(v); err != nil {
+		c.forget(id)
+		return nil, err
+	}
+	select {
+	case res := <-resc:
+		return res.Value, res.Err
+	case <-ctx.Done():
+		c.forget(id)
+		return nil, ctx.Err()
+	}
+}
+
+// complete passes the result of the command to its caller, if it is still
+// waiting.
+func (c *conn) complete(id int, value json.RawMessage, err error) {
+	c.Lock()
+	resc, ok := c.pending[id]
+	delete(c.pending, id)
+	c.Unlock()
+	if ok {
+		resc <- result{Value: value, Err: err}
+	}
+}
+
+// forget drops the pending command, so its result is discarded.
+func (c *conn) forget(id int) {
+	c.Lock()
+	delete(c.pending, id)
+	c.Unlock()
+}
+
+// close closes the connection and completes all pending commands with
+// ErrConnectionClosed.
+func (c *conn) close() error {
+	c.Lock()
+	defer c.Unlock()
+	c.closed = true
+	for id, resc := range c.pending {
+		resc <- result{Err: ErrConnectionClosed}
+		delete(c.pending, id)
+	}
+	if c.ws == nil {
+		return nil
+	}
+	return c.ws.Close()
+}
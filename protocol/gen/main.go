@@ -0,0 +1,360 @@
+// Command gen generates the protocol package from the protocol JSON schema.
+//
+// The schema has the format of the Chrome DevTools Protocol schema
+// (https://github.com/ChromeDevTools/devtools-protocol), restricted to the
+// domains, commands and events firefox supports. Each domain is generated into
+// its own file:
+//
+//	go run ./gen -schema schema.json -out .
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// schema is the protocol JSON schema.
+type schema struct {
+	Domains []domain `json:"domains"`
+}
+
+type domain struct {
+	Domain      string    `json:"domain"`
+	Description string    `json:"description"`
+	Types       []typeDef `json:"types"`
+	Commands    []command `json:"commands"`
+	Events      []command `json:"events"`
+}
+
+// typeDef is a named type, or the type of a property when ID is empty.
+type typeDef struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Type        string    `json:"type"`
+	Ref         string    `json:"$ref"`
+	Optional    bool      `json:"optional"`
+	Enum        []string  `json:"enum"`
+	Items       *typeDef  `json:"items"`
+	Properties  []typeDef `json:"properties"`
+}
+
+type command struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Parameters  []typeDef `json:"parameters"`
+	Returns     []typeDef `json:"returns"`
+}
+
+// initialisms are the words written in upper case in Go names.
+var initialisms = map[string]bool{
+	"api": true, "css": true, "dip": true, "dom": true, "html": true, "http": true, "https": true,
+	"id": true, "ip": true, "js": true, "json": true, "pdf": true, "ui": true, "uri": true,
+	"url": true, "utc": true, "xhr": true, "xml": true,
+}
+
+// messageFields are the Go names of the params of commands and events which
+// would clash with their Method func.
+var messageFields = map[string]string{
+	"Method": "HTTPMethod",
+}
+
+// pointerFields are the optional params, by "Domain.command.param", whose
+// default is not their zero value, e.g. the 1cm PDF margins. Their fields are
+// pointers, so the zero value can be sent.
+var pointerFields = map[string]bool{
+	"Page.printToPDF.marginTop":      true,
+	"Page.printToPDF.marginBottom":   true,
+	"Page.printToPDF.marginLeft":     true,
+	"Page.printToPDF.marginRight":    true,
+	"Page.printToPDF.headerTemplate": true,
+	"Page.printToPDF.footerTemplate": true,
+}
+
+func main() {
+	schemaPath := flag.String("schema", "schema.json", "protocol JSON schema")
+	out := flag.String("out", ".", "output directory")
+	flag.Parse()
+
+	files, err := generate(*schemaPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(*out, name), src, 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// generate returns the Go source files of the domains of the schema by file
+// name.
+func generate(schemaPath string) (map[string][]byte, error) {
+	b, err := os.ReadFile(schemaPath)
+	if err != nil {
+		return nil, err
+	}
+	s := schema{}
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	g := newGenerator(s)
+	files := map[string][]byte{}
+	for _, d := range s.Domains {
+		src, err := g.domain(d)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", d.Domain, err)
+		}
+		files[strings.ToLower(d.Domain)+".go"] = src
+	}
+	return files, nil
+}
+
+type generator struct {
+	types map[string]typeDef // named types by "Domain.ID"
+}
+
+func newGenerator(s schema) *generator {
+	g := &generator{types: map[string]typeDef{}}
+	for _, d := range s.Domains {
+		for _, t := range d.Types {
+			g.types[d.Domain+"."+t.ID] = t
+		}
+	}
+	return g
+}
+
+// domain returns the formatted Go source of the domain.
+func (g *generator) domain(d domain) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "// Code generated by protocol/gen from schema.json; DO NOT EDIT.\n\n")
+	fmt.Fprintf(buf, "package protocol\n\n")
+	body := &bytes.Buffer{}
+
+	for _, t := range d.Types {
+		name := qualify(d.Domain, t.ID)
+		comment(body, fmt.Sprintf("%s mirrors %s.%s.", name, d.Domain, t.ID), t.Description)
+		switch {
+		case t.Type == "object" && len(t.Properties) > 0:
+			fmt.Fprintf(body, "type %s struct {\n", name)
+			if err := g.fields(body, d.Domain, t.Properties, ""); err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(body, "}\n\n")
+		default:
+			typ, err := g.goType(d.Domain, typeDef{Type: t.Type, Items: t.Items})
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", t.ID, err)
+			}
+			fmt.Fprintf(body, "type %s %s\n\n", name, typ)
+			if len(t.Enum) > 0 {
+				fmt.Fprintf(body, "const (\n")
+				for _, v := range t.Enum {
+					fmt.Fprintf(body, "\t%s%s %s = %q\n", name, goName(v), name, v)
+				}
+				fmt.Fprintf(body, ")\n\n")
+			}
+		}
+	}
+
+	for _, c := range d.Commands {
+		name := qualify(d.Domain, goName(c.Name))
+		method := d.Domain + "." + c.Name
+		comment(body, fmt.Sprintf("%sParams are the parameters of %s.", name, method), c.Description)
+		if err := g.message(body, d.Domain, name+"Params", method, c.Parameters); err != nil {
+			return nil, fmt.Errorf("%s: %w", c.Name, err)
+		}
+		if len(c.Returns) > 0 {
+			comment(body, fmt.Sprintf("%sReturns is the result of %s.", name, method), "")
+			fmt.Fprintf(body, "type %sReturns struct {\n", name)
+			if err := g.fields(body, d.Domain, c.Returns, ""); err != nil {
+				return nil, fmt.Errorf("%s: %w", c.Name, err)
+			}
+			fmt.Fprintf(body, "}\n\n")
+		}
+	}
+
+	for _, e := range d.Events {
+		name := qualify(d.Domain, goName(e.Name))
+		method := d.Domain + "." + e.Name
+		comment(body, fmt.Sprintf("%sEvent is the %s event.", name, method), e.Description)
+		if err := g.message(body, d.Domain, name+"Event", method, e.Parameters); err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Name, err)
+		}
+	}
+
+	if bytes.Contains(body.Bytes(), []byte("json.RawMessage")) {
+		fmt.Fprintf(buf, "import \"encoding/json\"\n\n")
+	}
+	buf.Write(body.Bytes())
+	return format.Source(buf.Bytes())
+}
+
+// message writes the struct of the command params or event, and its Method.
+func (g *generator) message(w *bytes.Buffer, domain, name, method string, params []typeDef) error {
+	fmt.Fprintf(w, "type %s struct {\n", name)
+	if err := g.fields(w, domain, params, method); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "}\n\n")
+	fmt.Fprintf(w, "// Method returns %q.\n", method)
+	fmt.Fprintf(w, "func (%s) Method() string { return %q }\n\n", name, method)
+	return nil
+}
+
+// fields writes the struct fields of the properties, renamed with
+// messageFields and made pointers with pointerFields in the params and events
+// of the method, if not empty.
+func (g *generator) fields(w *bytes.Buffer, domain string, props []typeDef, method string) error {
+	for _, p := range props {
+		typ, err := g.goType(domain, p)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p.Name, err)
+		}
+		if p.Optional && pointerFields[method+"."+p.Name] {
+			typ = "*" + typ
+		}
+		desc := p.Description
+		if len(p.Enum) > 0 {
+			desc = strings.TrimSpace(desc + " One of " + strings.Join(p.Enum, ", ") + ".")
+		}
+		if desc != "" {
+			comment(w, "", desc)
+		}
+		tag := p.Name
+		if p.Optional {
+			tag += ",omitempty"
+		}
+		name := goName(p.Name)
+		if renamed, ok := messageFields[name]; ok && method != "" {
+			name = renamed
+		}
+		fmt.Fprintf(w, "%s %s `json:%q`\n", name, typ, tag)
+	}
+	return nil
+}
+
+// goType returns the Go type of the property. Optional structs are pointers.
+func (g *generator) goType(domain string, p typeDef) (string, error) {
+	if p.Ref != "" {
+		refDomain, id := domain, p.Ref
+		if i := strings.Index(p.Ref, "."); i >= 0 {
+			refDomain, id = p.Ref[:i], p.Ref[i+1:]
+		}
+		t, ok := g.types[refDomain+"."+id]
+		if !ok {
+			return "", fmt.Errorf("unknown type %s.%s", refDomain, id)
+		}
+		name := qualify(refDomain, id)
+		if p.Optional && t.Type == "object" && len(t.Properties) > 0 {
+			return "*" + name, nil
+		}
+		return name, nil
+	}
+	switch p.Type {
+	case "string":
+		return "string", nil
+	case "integer":
+		return "int", nil
+	case "number":
+		return "float64", nil
+	case "boolean":
+		return "bool", nil
+	case "any":
+		return "json.RawMessage", nil
+	case "object":
+		return "map[string]interface{}", nil
+	case "array":
+		if p.Items == nil {
+			return "", fmt.Errorf("array without items")
+		}
+		item, err := g.goType(domain, *p.Items)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + item, nil
+	default:
+		return "", fmt.Errorf("unknown type %q", p.Type)
+	}
+}
+
+// qualify returns the Go name of the domain item, prefixed with the domain
+// unless it already starts with it, e.g. PageFrameID but TargetInfo.
+func qualify(domain, name string) string {
+	name = goName(name)
+	if strings.HasPrefix(name, domain) {
+		return name
+	}
+	return domain + name
+}
+
+// goName returns the exported Go name of the schema name, e.g. FrameID for
+// frameId.
+func goName(name string) string {
+	s := ""
+	for _, word := range words(name) {
+		if lower := strings.ToLower(word); initialisms[lower] {
+			s += strings.ToUpper(word)
+		} else {
+			s += strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return s
+}
+
+// words splits the camel case or snake case name into words, e.g.
+// setExtraHTTPHeaders into set, Extra, HTTP and Headers.
+func words(name string) []string {
+	var words []string
+	runes := []rune(name)
+	start := 0
+	for i := 1; i <= len(runes); i++ {
+		split := i == len(runes) || runes[i] == '_' || runes[i] == '-'
+		if !split && unicode.IsUpper(runes[i]) {
+			prevLower := !unicode.IsUpper(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			split = prevLower || nextLower
+		}
+		if split {
+			if word := strings.Trim(string(runes[start:i]), "_-"); word != "" {
+				words = append(words, word)
+			}
+			start = i
+		}
+	}
+	return words
+}
+
+// comment writes the doc comment made of the summary and the description,
+// wrapped at 80 columns.
+func comment(w *bytes.Buffer, summary, description string) {
+	var paragraphs []string
+	if summary != "" {
+		paragraphs = append(paragraphs, summary)
+	}
+	if description = strings.TrimSpace(description); description != "" {
+		paragraphs = append(paragraphs, description)
+	}
+	for i, p := range paragraphs {
+		if i > 0 {
+			fmt.Fprintf(w, "//\n")
+		}
+		line := "//"
+		for _, word := range strings.Fields(p) {
+			if len(line)+1+len(word) > 77 && line != "//" {
+				fmt.Fprintf(w, "%s\n", line)
+				line = "//"
+			}
+			line += " " + word
+		}
+		fmt.Fprintf(w, "%s\n", line)
+	}
+}
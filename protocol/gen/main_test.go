@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGoName(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		want string
+	}{
+		{"frameId", "FrameID"},
+		{"documentURL", "DocumentURL"},
+		{"setExtraHTTPHeaders", "SetExtraHTTPHeaders"},
+		{"preferCSSPageSize", "PreferCSSPageSize"},
+		{"remoteIPAddress", "RemoteIPAddress"},
+		{"address_bar", "AddressBar"},
+		{"XHR", "XHR"},
+		{"printToPDF", "PrintToPDF"},
+	} {
+		if got := goName(tt.name); got != tt.want {
+			t.Errorf("goName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestQualify(t *testing.T) {
+	if got := qualify("Page", "FrameId"); got != "PageFrameID" {
+		t.Errorf("qualify() = %q, want PageFrameID", got)
+	}
+	if got := qualify("Target", "targetCreated"); got != "TargetCreated" {
+		t.Errorf("qualify() = %q, want TargetCreated", got)
+	}
+}
+
+// TestGenerated checks that the generated files are up to date with the
+// schema.
+func TestGenerated(t *testing.T) {
+	files, err := generate(filepath.Join("..", "schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, src := range files {
+		current, err := os.ReadFile(filepath.Join("..", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(current, src) {
+			t.Errorf("%s is out of date, run go generate ./protocol", name)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+// Package protocol provides the typed params, results and events of the
+// Chrome DevTools Protocol domains firefox supports.
+//
+// The types are generated from schema.json, a subset of the upstream protocol
+// schema. Each command has a <Domain><Command>Params type, and a
+// <Domain><Command>Returns type if it has a result; each event has a
+// <Domain><Event>Event type.
+package protocol
+
+//go:generate go run ./gen -schema schema.json -out .
+
+// Command is the params of a command, or an event, which knows its method,
+// e.g. "Page.navigate".
+type Command interface {
+	Method() string
+}
@@ -0,0 +1,121 @@
+package gofirefox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// testConn returns a connection to a websocket server which receives the
+// commands sent but never replies.
+func testConn(t *testing.T) (*conn, <-chan json.RawMessage) {
+	sent := make(chan json.RawMessage, 10)
+	server := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		for {
+			var msg json.RawMessage
+			if err := websocket.JSON.Receive(ws, &msg); err != nil {
+				return
+			}
+			sent <- msg
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	c := newConn(0)
+	if err := c.dial("ws" + strings.TrimPrefix(server.URL, "http")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.close() })
+	return c, sent
+}
+
+// pendingCount returns the number of commands waiting for their result.
+func (c *conn) pendingCount() int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.pending)
+}
+
+func TestConnCall(t *testing.T) {
+	c, sent := testConn(t)
+	id := c.next()
+	done := make(chan error, 1)
+	go func() {
+		v, err := c.call(context.Background(), id, map[string]int{"id": id})
+		if err == nil && string(v) != `{"ok":true}` {
+			err = errors.New("unexpected result " + string(v))
+		}
+		done <- err
+	}()
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("command was not sent")
+	}
+	c.complete(id, json.RawMessage(`{"ok":true}`), nil)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if n := c.pendingCount(); n != 0 {
+		t.Errorf("%d commands still pending", n)
+	}
+	// late or unknown results are dropped
+	c.complete(id, nil, nil)
+}
+
+func TestConnClose(t *testing.T) {
+	c, sent := testConn(t)
+	done := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		id := c.next()
+		go func() {
+			_, err := c.call(context.Background(), id, map[string]int{"id": id})
+			done <- err
+		}()
+		<-sent
+	}
+	c.close()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-done:
+			if !errors.Is(err, ErrConnectionClosed) {
+				t.Errorf("got %v, want ErrConnectionClosed", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("pending call did not fail")
+		}
+	}
+	if _, err := c.call(context.Background(), c.next(), nil); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("call after close: got %v, want ErrConnectionClosed", err)
+	}
+	if err := c.send(nil); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("send after close: got %v, want ErrConnectionClosed", err)
+	}
+}
+
+func TestConnCallCanceled(t *testing.T) {
+	c, sent := testConn(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	id := c.next()
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.call(ctx, id, map[string]int{"id": id})
+		done <- err
+	}()
+	<-sent
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	if c.pendingCount() != 0 {
+		t.Error("canceled command is still pending")
+	}
+	// the result arriving after the caller gave up is dropped
+	c.complete(id, json.RawMessage(`{}`), nil)
+}
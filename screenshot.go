@@ -0,0 +1,83 @@
+package gofirefox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ImageFormat is the format of a screenshot.
+type ImageFormat string
+
+const (
+	ImagePNG  ImageFormat = "png"
+	ImageJPEG ImageFormat = "jpeg"
+)
+
+// ErrElementNotFound is returned when no element matches a CSS selector.
+var ErrElementNotFound = errors.New("gofirefox: element not found")
+
+// ScreenshotOptions configure UI.Screenshot.
+type ScreenshotOptions struct {
+	// Format is ImagePNG by default.
+	Format ImageFormat
+	// Quality is the ImageJPEG quality from 1 to 100, the browser default if
+	// zero.
+	Quality int
+	// FullPage captures the whole page instead of the viewport.
+	FullPage bool
+	// Selector captures the first element matching the CSS selector instead
+	// of the viewport, if set.
+	Selector string
+}
+
+// Rect is an area of the page in CSS pixels.
+type Rect struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// elementRectScript returns the area of the first element matching the
+// selector relative to the document, or null if there is none.
+const elementRectScript = `((selector) => {
+	const element = document.querySelector(selector);
+	if (!element) {
+		return null;
+	}
+	const rect = element.getBoundingClientRect();
+	return {x: rect.left + window.scrollX, y: rect.top + window.scrollY, width: rect.width, height: rect.height};
+})(%s)`
+
+func (t *tab) Screenshot(ctx context.Context, opts ScreenshotOptions) ([]byte, error) {
+	if err := t.checkOpen(); err != nil {
+		return nil, err
+	}
+	switch opts.Format {
+	case "":
+		opts.Format = ImagePNG
+	case ImagePNG, ImageJPEG:
+	default:
+		return nil, fmt.Errorf("unknown image format %q", opts.Format)
+	}
+	if opts.Quality < 0 || opts.Quality > 100 {
+		return nil, fmt.Errorf("invalid image quality %d", opts.Quality)
+	}
+
+	var clip *Rect
+	if opts.Selector != "" {
+		selector, _ := json.Marshal(opts.Selector)
+		if err := t.EvalInto(ctx, fmt.Sprintf(elementRectScript, selector), &clip); err != nil {
+			return nil, err
+		}
+		if clip == nil {
+			return nil, fmt.Errorf("%w: %s", ErrElementNotFound, opts.Selector)
+		}
+		if clip.Width <= 0 || clip.Height <= 0 {
+			return nil, fmt.Errorf("element %s is not visible", opts.Selector)
+		}
+	}
+	return t.firefox.proto.Screenshot(ctx, t.target, opts.Format, opts.Quality, opts.FullPage, clip)
+}
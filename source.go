@@ -0,0 +1,178 @@
+package gofirefox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ProfileSource provides the user.js document copied into the profile.
+type ProfileSource interface {
+	// Fetch returns the user.js content and, if signature is true, its
+	// minisign signature.
+	Fetch(ctx context.Context, signature bool) (data, sig []byte, err error)
+	// String describes the source in logs and errors.
+	String() string
+}
+
+// conditionalSource is implemented by the sources which can revalidate the
+// cached copy, and so are cached.
+type conditionalSource interface {
+	fetchIfModified(ctx context.Context, cached *cacheEntry, signature bool, logger *slog.Logger) (*cacheEntry, error)
+}
+
+// loggingSource is implemented by the sources which log, so they can be
+// given the logger of the UI.
+type loggingSource interface {
+	withLogger(logger *slog.Logger) ProfileSource
+}
+
+// signatureSuffix is appended to the user.js location to get its signature.
+const signatureSuffix = ".minisig"
+
+// maxProfileSize limits the size of user.js and its signature
+const maxProfileSize = 10 << 20
+
+// ParseProfileLocation returns the source for the user.js location, which is
+// a http(s):// or file:// URL, or a local path.
+func ParseProfileLocation(location string) (ProfileSource, error) {
+	if filepath.IsAbs(location) || !strings.Contains(location, "://") {
+		return ProfileFromFile(location), nil
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid profile location %q: %s", location, err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return ProfileFromURL(location), nil
+	case "file":
+		path := u.Path
+		if u.Host != "" && u.Host != "localhost" {
+			return nil, fmt.Errorf("invalid profile location %q: remote file host", location)
+		}
+		// file:///C:/dir/user.js on windows
+		if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+			path = path[1:]
+		}
+		return ProfileFromFile(filepath.FromSlash(path)), nil
+	default:
+		return nil, fmt.Errorf("invalid profile location %q: unsupported scheme %q", location, u.Scheme)
+	}
+}
+
+// fileSource reads user.js from a local file. The minisign signature is read
+// from the file with the .minisig suffix.
+type fileSource struct {
+	path string
+}
+
+// ProfileFromFile returns the source reading user.js from the local file.
+func ProfileFromFile(path string) ProfileSource {
+	return &fileSource{path: path}
+}
+
+func (s *fileSource) String() string {
+	return s.path
+}
+
+func (s *fileSource) Fetch(ctx context.Context, signature bool) ([]byte, []byte, error) {
+	data, err := readFileLimited(s.path)
+	if err != nil || !signature {
+		return data, nil, err
+	}
+	sig, err := readFileLimited(s.path + signatureSuffix)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read signature: %s", err)
+	}
+	return data, sig, nil
+}
+
+func readFileLimited(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, maxProfileSize))
+}
+
+// readerSource reads user.js from an io.Reader. The content is read once and
+// reused on each start.
+type readerSource struct {
+	r    io.Reader
+	once sync.Once
+	data []byte
+	err  error
+}
+
+// ProfileFromReader returns the source reading user.js from r. Signature
+// verification is not supported, as there is no signature to read.
+func ProfileFromReader(r io.Reader) ProfileSource {
+	return &readerSource{r: r}
+}
+
+func (s *readerSource) String() string {
+	return "reader"
+}
+
+func (s *readerSource) Fetch(ctx context.Context, signature bool) ([]byte, []byte, error) {
+	if signature {
+		return nil, nil, errors.New("signature is not available")
+	}
+	s.once.Do(func() {
+		s.data, s.err = io.ReadAll(io.LimitReader(s.r, maxProfileSize))
+	})
+	return append([]byte(nil), s.data...), nil, s.err
+}
+
+// loadProfile returns user.js from the source, verified with the verifier.
+// Remote sources are cached: the cached copy is revalidated and used as the
+// last known good copy if fetching or verification fails.
+func loadProfile(ctx context.Context, src ProfileSource, cache *profileCache, verifier *profileVerifier, logger *slog.Logger) ([]byte, error) {
+	cs, ok := src.(conditionalSource)
+	if !ok {
+		cache = nil
+	}
+
+	cached := cache.load(src.String())
+	if cached != nil {
+		if err := verifier.verify(cached.data, cached.Signature); err != nil {
+			logger.Warn("ignoring cached user.js", "err", err)
+			cached = nil
+		}
+	}
+
+	entry, err := func() (*cacheEntry, error) {
+		if ok {
+			return cs.fetchIfModified(ctx, cached, verifier.needsSignature(), logger)
+		}
+		data, sig, err := src.Fetch(ctx, verifier.needsSignature())
+		return &cacheEntry{URL: src.String(), Signature: sig, data: data}, err
+	}()
+	if err == nil && entry != cached {
+		err = verifier.verify(entry.data, entry.Signature)
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to load user.js from %s: %w", src, err)
+		if cached == nil {
+			return nil, err
+		}
+		logger.Warn("using the last known good user.js", "err", err)
+		return cached.data, nil
+	}
+
+	if entry != cached {
+		if err := cache.store(entry); err != nil {
+			logger.Warn("failed to cache user.js", "err", err)
+		}
+	}
+	return entry.data, nil
+}
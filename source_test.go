@@ -0,0 +1,182 @@
+package gofirefox
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// minisign returns the public key and the signature of data in the minisign
+// format, the default one signing the BLAKE2b hash of data if prehashed, the
+// legacy one otherwise.
+func minisign(t *testing.T, data []byte, prehashed bool) (string, []byte) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := []byte("keyid123")
+	key := append(append([]byte("Ed"), id...), pub...)
+	algorithm := "Ed"
+	if prehashed {
+		sum := blake2b.Sum512(data)
+		algorithm, data = "ED", sum[:]
+	}
+	sig := ed25519.Sign(priv, data)
+	comment := "timestamp:0"
+	global := ed25519.Sign(priv, append(append([]byte{}, sig...), comment...))
+	signature := fmt.Sprintf("untrusted comment: test\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(append(append([]byte(algorithm), id...), sig...)),
+		comment,
+		base64.StdEncoding.EncodeToString(global))
+	return base64.StdEncoding.EncodeToString(key), []byte(signature)
+}
+
+func TestFetchProfile(t *testing.T) {
+	userJS := []byte(`user_pref("a", 1);`)
+	publicKey, signature := minisign(t, userJS, false)
+
+	body, requests := userJS, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/user.js.minisig" {
+			w.Write(signature)
+			return
+		}
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` && string(body) == string(userJS) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	cache := newProfileCache(t.TempDir())
+	sum := sha256.Sum256(userJS)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	url := srv.URL + "/user.js"
+	src := &httpSource{url: url, client: srv.Client()}
+
+	for name, verifier := range map[string][2]string{
+		"sha256":    {hex.EncodeToString(sum[:]), ""},
+		"signature": {"", publicKey},
+	} {
+		t.Run(name, func(t *testing.T) {
+			v, err := newProfileVerifier(verifier[0], verifier[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			body = userJS
+			data, err := loadProfile(ctx, src, cache, v, logger)
+			if err != nil || string(data) != string(userJS) {
+				t.Fatalf("got %q, %v", data, err)
+			}
+			if e := cache.load(url); e == nil || e.ETag != `"v1"` {
+				t.Fatalf("not cached: %+v", e)
+			}
+
+			// tampered document falls back to the last known good copy
+			body = []byte(`user_pref("a", 2);`)
+			data, err = loadProfile(ctx, src, cache, v, logger)
+			if err != nil || string(data) != string(userJS) {
+				t.Fatalf("got %q, %v", data, err)
+			}
+
+			// without the cache it fails
+			_, err = loadProfile(ctx, src, nil, v, logger)
+			if !errors.Is(err, ErrProfileVerification) {
+				t.Fatalf("got %v, want %v", err, ErrProfileVerification)
+			}
+		})
+	}
+	if requests == 0 {
+		t.Fatal("no requests")
+	}
+}
+
+func TestMinisignVerify(t *testing.T) {
+	data := []byte(`user_pref("a", 1);`)
+	for name, prehashed := range map[string]bool{"legacy": false, "prehashed": true} {
+		t.Run(name, func(t *testing.T) {
+			publicKey, signature := minisign(t, data, prehashed)
+			v, err := newProfileVerifier("", publicKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := v.verify(data, signature); err != nil {
+				t.Fatal(err)
+			}
+			if err := v.verify([]byte(`user_pref("a", 2);`), signature); !errors.Is(err, ErrProfileVerification) {
+				t.Errorf("tampered document: got %v, want %v", err, ErrProfileVerification)
+			}
+			tampered := strings.Replace(string(signature), "timestamp:0", "timestamp:1", 1)
+			if err := v.verify(data, []byte(tampered)); !errors.Is(err, ErrProfileVerification) {
+				t.Errorf("tampered comment: got %v, want %v", err, ErrProfileVerification)
+			}
+		})
+	}
+}
+
+func TestParseProfileLocation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "user.js")
+	if err := os.WriteFile(path, []byte(`user_pref("a", 1);`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, location := range []string{path, "file://" + filepath.ToSlash(path)} {
+		src, err := ParseProfileLocation(location)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := loadProfile(context.Background(), src, nil, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		if err != nil || string(data) != `user_pref("a", 1);` {
+			t.Errorf("%s: got %q, %v", location, data, err)
+		}
+	}
+
+	if src, err := ParseProfileLocation("https://example.com/user.js"); err != nil || src.String() != "https://example.com/user.js" {
+		t.Errorf("got %v, %v", src, err)
+	}
+	if _, err := ParseProfileLocation("ftp://example.com/user.js"); err == nil {
+		t.Error("expected error for unsupported scheme")
+	}
+	_, err := loadProfile(context.Background(), ProfileFromFile(filepath.Join(dir, "missing.js")), nil, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("got %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestFetchLogger(t *testing.T) {
+	// an unreachable server, connections are refused
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	logs := &strings.Builder{}
+	src := ProfileFromURL(srv.URL + "/user.js").(loggingSource).withLogger(slog.New(slog.NewTextHandler(logs, nil)))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, _, err := src.Fetch(ctx, false); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Fetch() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if !strings.Contains(logs.String(), "retrying") {
+		t.Errorf("retry not logged to the source logger: %q", logs)
+	}
+}
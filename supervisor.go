@@ -0,0 +1,103 @@
+package gofirefox
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrCrashLoop is returned by Err when firefox has ended too often within
+// RestartPolicy.CrashLoopWindow to be restarted again.
+var ErrCrashLoop = errors.New("gofirefox: firefox crash loop")
+
+// RestartPolicy makes firefox restarted when it crashes, is killed, its
+// window is closed or it fails to start. Firefox is restarted with the last
+// URL of the main tab, and the bindings are reinstalled. Zero fields use the
+// defaults.
+type RestartPolicy struct {
+	// MaxRestarts is the maximum number of restarts, unlimited if zero.
+	MaxRestarts int
+	// InitialBackoff is the delay before the first restart, 1 second by
+	// default. It is doubled on each restart up to MaxBackoff, 1 minute by
+	// default, and reset once firefox runs longer than CrashLoopWindow.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// CrashLoopRestarts restarts within CrashLoopWindow are a crash loop
+	// which stops the restarts with ErrCrashLoop. 5 restarts within 1 minute
+	// by default.
+	CrashLoopRestarts int
+	CrashLoopWindow   time.Duration
+	// OnRestart is called before each restart. It must not block.
+	OnRestart func(RestartEvent)
+}
+
+// RestartEvent reports the restart of firefox.
+type RestartEvent struct {
+	// Restart is the number of the restart, starting at 1.
+	Restart int
+	// Cause is why firefox has ended.
+	Cause error
+	// Backoff is how long the restart is delayed.
+	Backoff time.Duration
+}
+
+// supervisor decides whether and when firefox is restarted.
+type supervisor struct {
+	policy   RestartPolicy
+	enabled  bool
+	restarts int
+	recent   []time.Time // restarts within the crash loop window
+	backoff  time.Duration
+}
+
+func newSupervisor(p *RestartPolicy) *supervisor {
+	if p == nil {
+		return &supervisor{}
+	}
+	s := &supervisor{policy: *p, enabled: true}
+	if s.policy.InitialBackoff <= 0 {
+		s.policy.InitialBackoff = time.Second
+	}
+	if s.policy.MaxBackoff <= 0 {
+		s.policy.MaxBackoff = time.Minute
+	}
+	if s.policy.CrashLoopRestarts <= 0 {
+		s.policy.CrashLoopRestarts = 5
+	}
+	if s.policy.CrashLoopWindow <= 0 {
+		s.policy.CrashLoopWindow = time.Minute
+	}
+	return s
+}
+
+// next returns the restart of firefox which has ended with cause at now after
+// running for ran, or the error ending firefox for good.
+func (s *supervisor) next(cause error, ran time.Duration, now time.Time) (RestartEvent, error) {
+	if !s.enabled || (s.policy.MaxRestarts > 0 && s.restarts >= s.policy.MaxRestarts) {
+		return RestartEvent{}, cause
+	}
+
+	recent := s.recent[:0]
+	for _, t := range s.recent {
+		if now.Sub(t) < s.policy.CrashLoopWindow {
+			recent = append(recent, t)
+		}
+	}
+	s.recent = recent
+	if len(s.recent) >= s.policy.CrashLoopRestarts {
+		return RestartEvent{}, fmt.Errorf("%w: %d restarts within %s, last cause: %s",
+			ErrCrashLoop, len(s.recent), s.policy.CrashLoopWindow, cause)
+	}
+
+	switch {
+	case s.backoff == 0 || ran >= s.policy.CrashLoopWindow:
+		s.backoff = s.policy.InitialBackoff
+	case s.backoff*2 > s.policy.MaxBackoff:
+		s.backoff = s.policy.MaxBackoff
+	default:
+		s.backoff *= 2
+	}
+	s.restarts++
+	s.recent = append(s.recent, now)
+	return RestartEvent{Restart: s.restarts, Cause: cause, Backoff: s.backoff}, nil
+}
@@ -0,0 +1,70 @@
+package gofirefox
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSupervisor(t *testing.T) {
+	now := time.Now()
+	for _, test := range []struct {
+		name    string
+		policy  *RestartPolicy
+		ran     []time.Duration // how long firefox ran before each restart
+		backoff []time.Duration // expected backoffs, zero if firefox is not restarted
+		err     error           // expected error of the last restart
+	}{
+		{
+			name:    "disabled",
+			ran:     []time.Duration{time.Second},
+			backoff: []time.Duration{0},
+			err:     ErrCrashed,
+		},
+		{
+			name:    "exponential backoff",
+			policy:  &RestartPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second, CrashLoopRestarts: 10},
+			ran:     []time.Duration{0, 0, 0, 0},
+			backoff: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second},
+		},
+		{
+			name:    "backoff reset after stable run",
+			policy:  &RestartPolicy{InitialBackoff: time.Second, CrashLoopWindow: time.Minute},
+			ran:     []time.Duration{0, 0, time.Hour},
+			backoff: []time.Duration{time.Second, 2 * time.Second, time.Second},
+		},
+		{
+			name:    "max restarts",
+			policy:  &RestartPolicy{MaxRestarts: 2, InitialBackoff: time.Second},
+			ran:     []time.Duration{time.Hour, time.Hour, time.Hour},
+			backoff: []time.Duration{time.Second, time.Second, 0},
+			err:     ErrCrashed,
+		},
+		{
+			name:    "crash loop",
+			policy:  &RestartPolicy{CrashLoopRestarts: 2, InitialBackoff: time.Second},
+			ran:     []time.Duration{0, 0, 0},
+			backoff: []time.Duration{time.Second, 2 * time.Second, 0},
+			err:     ErrCrashLoop,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s := newSupervisor(test.policy)
+			at := now
+			for i, ran := range test.ran {
+				at = at.Add(ran)
+				event, err := s.next(ErrCrashed, ran, at)
+				if event.Backoff != test.backoff[i] {
+					t.Errorf("restart %d backoff = %s, want %s", i+1, event.Backoff, test.backoff[i])
+				}
+				if i == len(test.ran)-1 && test.err != nil {
+					if !errors.Is(err, test.err) {
+						t.Errorf("error = %v, want %v", err, test.err)
+					}
+				} else if err != nil {
+					t.Errorf("restart %d error = %v", i+1, err)
+				}
+			}
+		})
+	}
+}
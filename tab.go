@@ -0,0 +1,275 @@
+package gofirefox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// Tab is a browser tab.
+type Tab interface {
+	// ID returns the target ID (CDP) or the browsing context ID (BiDi) of the
+	// tab.
+	ID() string
+	// URL returns the last known URL of the tab.
+	URL() string
+	Load(url string) error
+	// LoadAndWait navigates the tab to the url and waits until the page
+	// reaches the load state. Failed navigations are returned as
+	// *NavigationError.
+	LoadAndWait(ctx context.Context, url string, until LoadState) error
+	// WaitForNavigation waits until the next navigation of the tab, e.g.
+	// triggered by a link or a script, reaches the load state. A navigation
+	// which has not loaded yet counts. Failed navigations are returned as
+	// *NavigationError.
+	WaitForNavigation(ctx context.Context, until LoadState) error
+	Eval(ctx context.Context, js string) (Value, error)
+	EvalInto(ctx context.Context, js string, out interface{}) error
+	// Screenshot captures the tab, see UI.Screenshot.
+	Screenshot(ctx context.Context, opts ScreenshotOptions) ([]byte, error)
+	// PrintToPDF prints the tab, see UI.PrintToPDF.
+	PrintToPDF(ctx context.Context, opts PDFOptions) ([]byte, error)
+	// Activate brings the tab to the front.
+	Activate() error
+	// Close closes the tab. Closing the main tab ends firefox.
+	Close() error
+	// Send sends the raw protocol command to the tab, see UI.Send.
+	Send(ctx context.Context, method string, params interface{}) (json.RawMessage, error)
+}
+
+// ErrTabClosed is returned for commands sent to a tab which was closed.
+var ErrTabClosed = errors.New("gofirefox: tab closed")
+
+type tab struct {
+	firefox *firefox
+	target  string
+
+	// ready is closed once the tab is attached, or attaching has failed
+	ready  chan struct{}
+	err    error
+	url    string
+	closed bool
+	nav    navigation
+
+	// allowedURL is the last URL allowed by the navigation policy, and
+	// blockedURL the one being blocked. enforced are the URLs the policy has
+	// sent the tab to since it last navigated on its own.
+	allowedURL string
+	blockedURL string
+	enforced   map[string]bool
+}
+
+func (t *tab) ID() string {
+	return t.target
+}
+
+func (t *tab) URL() string {
+	t.firefox.Lock()
+	defer t.firefox.Unlock()
+	return t.url
+}
+
+// checkOpen returns ErrTabClosed if the tab was closed.
+func (t *tab) checkOpen() error {
+	t.firefox.Lock()
+	defer t.firefox.Unlock()
+	if t.closed {
+		return ErrTabClosed
+	}
+	return nil
+}
+
+func (t *tab) Load(url string) error {
+	if err := t.checkOpen(); err != nil {
+		return err
+	}
+	return t.firefox.proto.Navigate(context.Background(), t.target, url)
+}
+
+func (t *tab) Eval(ctx context.Context, js string) (Value, error) {
+	if err := t.checkOpen(); err != nil {
+		return nil, err
+	}
+	return t.firefox.proto.Evaluate(ctx, t.target, "", js)
+}
+
+func (t *tab) EvalInto(ctx context.Context, js string, out interface{}) error {
+	v, err := t.Eval(ctx, js)
+	if err != nil {
+		return err
+	}
+	return v.To(out)
+}
+
+func (t *tab) Send(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	if err := t.checkOpen(); err != nil {
+		return nil, err
+	}
+	return t.firefox.proto.Send(ctx, t.target, method, params)
+}
+
+func (t *tab) Activate() error {
+	if err := t.checkOpen(); err != nil {
+		return err
+	}
+	return t.firefox.proto.ActivateTab(context.Background(), t.target)
+}
+
+func (t *tab) Close() error {
+	if err := t.checkOpen(); err != nil {
+		return err
+	}
+	return t.firefox.proto.CloseTab(context.Background(), t.target)
+}
+
+// attach attaches to the tab and installs the bindings. Attaching to the same target again waits
+// for the first attach to complete.
+func (c *firefox) attach(ctx context.Context, target string) (*tab, error) {
+	c.Lock()
+	t, ok := c.targets[target]
+	if !ok {
+		t = &tab{firefox: c, target: target, ready: make(chan struct{})}
+		c.targets[target] = t
+		c.tabs = append(c.tabs, t)
+	}
+	c.Unlock()
+	if ok {
+		select {
+		case <-t.ready:
+			return t, t.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	err := func() error {
+		if err := c.proto.Attach(ctx, target); err != nil {
+			return err
+		}
+
+		for _, name := range c.bindingNames() {
+			if err := t.installBinding(ctx, name); err != nil {
+				c.config.logger.Warn("failed to install binding", "target", target, "binding", name, "err", err)
+			}
+		}
+		if c.config.IdleTimeout > 0 {
+			if err := t.installScript(ctx, idleExpr()); err != nil {
+				c.config.logger.Warn("failed to install input listener", "target", target, "err", err)
+			}
+		}
+		if patterns, navigations := c.interceptPatterns(), c.guard.restricts(); len(patterns) > 0 || navigations {
+			if err := c.proto.Intercept(ctx, target, patterns, navigations); err != nil {
+				c.config.logger.Warn("failed to intercept requests", "target", target, "err", err)
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		c.removeTab(target)
+	}
+	t.err = err
+	close(t.ready)
+	return t, err
+}
+
+// removeTab forgets the closed tab.
+func (c *firefox) removeTab(target string) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.popups, target)
+	t, ok := c.targets[target]
+	if !ok {
+		return
+	}
+	t.closed = true
+	t.update()
+	delete(c.targets, target)
+	for i := range c.tabs {
+		if c.tabs[i] == t {
+			c.tabs = append(c.tabs[:i], c.tabs[i+1:]...)
+			break
+		}
+	}
+}
+
+// newTab opens a new tab with the url.
+func (c *firefox) newTab(ctx context.Context, url string) (*tab, error) {
+	if url == "" {
+		url = "about:blank"
+	}
+	target, err := c.proto.NewTab(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return c.attach(ctx, target)
+}
+
+// mainTab returns the tab opened on start, or nil if it is not attached.
+func (c *firefox) mainTab() *tab {
+	c.Lock()
+	t, ok := c.targets[c.target]
+	c.Unlock()
+	if !ok {
+		return nil
+	}
+	select {
+	case <-t.ready:
+		if t.err != nil {
+			return nil
+		}
+		return t
+	default:
+		return nil
+	}
+}
+
+// setMainTab makes the tab the main tab, listed first.
+func (c *firefox) setMainTab(t *tab) {
+	c.Lock()
+	defer c.Unlock()
+	c.target = t.target
+	tabs := []*tab{t}
+	for _, other := range c.tabs {
+		if other != t {
+			tabs = append(tabs, other)
+		}
+	}
+	c.tabs = tabs
+}
+
+// waitMainTab waits until firefox has started and returns its main tab.
+func (c *firefox) waitMainTab(ctx context.Context) (*tab, error) {
+	c.Lock()
+	started := c.started
+	c.Unlock()
+	select {
+	case <-started:
+	case <-c.done:
+		return nil, ErrConnectionClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	t := c.mainTab()
+	if t == nil {
+		return nil, ErrConnectionClosed
+	}
+	return t, nil
+}
+
+// attachedTabs returns all attached tabs, in the order they were opened.
+func (c *firefox) attachedTabs() []*tab {
+	c.Lock()
+	tabs := append([]*tab{}, c.tabs...)
+	c.Unlock()
+	attached := tabs[:0]
+	for _, t := range tabs {
+		select {
+		case <-t.ready:
+			if t.err == nil {
+				attached = append(attached, t)
+			}
+		default:
+		}
+	}
+	return attached
+}
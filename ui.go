@@ -2,36 +2,143 @@ package gofirefox
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"reflect"
 	"strings"
 )
 
 // UI interface allows talking to the HTML5 UI from Go.
 type UI interface {
+	// Load navigates the main tab to the url.
 	Load(url string) error
+	// LoadAndWait navigates the main tab to the url and waits until the page
+	// reaches the load state, e.g. LoadStateLoad. If firefox is starting, it
+	// waits for the main tab first. Failed navigations, e.g. because the host
+	// name could not be resolved or the page has an HTTP error status, are
+	// returned as *NavigationError.
+	LoadAndWait(ctx context.Context, url string, until LoadState) error
+	// WaitForNavigation waits until the next navigation of the main tab, e.g.
+	// triggered by a link or a script, reaches the load state. A navigation
+	// which has not loaded yet counts, so it can be called right after the
+	// action triggering the navigation. If firefox is starting, it waits for
+	// the main tab first.
+	WaitForNavigation(ctx context.Context, until LoadState) error
+	// Eval evaluates the JS expression in the main tab and returns its result.
+	// If the expression returns a promise, Eval waits for it to settle. JS
+	// exceptions and rejected promises are returned as *JSException.
+	Eval(ctx context.Context, js string) (Value, error)
+	// EvalInto evaluates the JS expression and decodes its result into out.
+	// out is left untouched if the result is undefined or null, or if an
+	// error is returned.
+	EvalInto(ctx context.Context, js string, out interface{}) error
+	// Bind makes the Go function f callable from JS as window[name]. Call
+	// arguments are decoded into the parameters of f, and the JS call returns a
+	// promise resolved with the result of f or rejected with its error. f may
+	// return nothing, a value, an error, or a value and an error. A call with
+	// the wrong number of arguments, or a panic of f, rejects the promise too.
+	// Bindings are reinstalled after each navigation.
+	Bind(name string, f interface{}) error
 
+	// Screenshot captures the main tab as PNG or JPEG: its viewport, the whole
+	// page if opts.FullPage, or the first element matching opts.Selector.
+	// ErrElementNotFound is returned if no element matches.
+	Screenshot(ctx context.Context, opts ScreenshotOptions) ([]byte, error)
+	// PrintToPDF prints the document of the main tab to PDF, also in headless
+	// mode, see WithHeadless.
+	PrintToPDF(ctx context.Context, opts PDFOptions) ([]byte, error)
+
+	// Send sends the raw protocol command, e.g. "Emulation.setDeviceMetricsOverride",
+	// to the main tab and returns its raw result, for the commands the UI
+	// doesn't wrap. params are JSON encoded. Errors returned by the browser
+	// are *ProtocolError. With BiDi, commands are sent as is, e.g. with the
+	// browsing context in params.
+	Send(ctx context.Context, method string, params interface{}) (json.RawMessage, error)
+
+	// NewTab opens a new tab with the url, or a blank page if url is empty.
+	// Bindings are available in all tabs.
+	NewTab(url string) (Tab, error)
+	// Tabs returns the open tabs, the main tab first. Tabs opened by the page,
+	// e.g. popups, are included.
+	Tabs() []Tab
+
+	// Console returns the messages logged to the JS console of all tabs. Up
+	// to Config.EventBuffer messages are buffered from the first call on,
+	// further messages are dropped until the channel is read. The channel is
+	// never closed, select on Done to stop reading.
+	Console() <-chan ConsoleMessage
+	// Exceptions returns the uncaught JS exceptions of all tabs, buffered
+	// and dropped like the Console messages.
+	Exceptions() <-chan JSException
+	// DroppedEvents returns the number of console messages and exceptions
+	// dropped because their buffer was full.
+	DroppedEvents() (console, exceptions uint64)
+	// IdleResets returns the session resets of WithIdleReset, buffered and
+	// dropped like the Console messages.
+	IdleResets() <-chan IdleResetEvent
+
+	// On calls handler with the params of each event of the method sent by
+	// any tab, e.g. "Network.responseReceived", until unsubscribe is called
+	// or firefox has ended. Handlers are called from their own goroutine in
+	// the order of the events, up to Config.EventBuffer events are buffered
+	// for slow handlers and further events are dropped. Subscriptions survive
+	// restarts. With BiDi, only the events the session subscribes to are
+	// sent.
+	On(method string, handler func(params json.RawMessage)) (unsubscribe func())
+
+	// Intercept pauses the requests of all tabs whose URL matches the glob
+	// pattern, e.g. "https://api.example.com/*", and of one of the resource
+	// types if any, and passes them to handler. '*' matches any characters,
+	// '?' any one character, and '\' escapes the next character. handler runs
+	// in its own goroutine and may continue, fulfill or fail the request;
+	// requests it leaves unhandled are continued once it returns. The first
+	// interceptor matching a request handles it. Interceptors survive
+	// restarts until remove is called.
+	Intercept(pattern string, handler func(*InterceptedRequest), types ...ResourceType) (remove func(), err error)
+
+	// Run starts firefox and blocks until it exits. It returns nil if firefox
+	// was closed with Close, by the user, or because ctx is done, and the reason
+	// why firefox has ended otherwise.
 	Run(ctx context.Context) error
+	// Done returns a channel which is closed when firefox has exited or its
+	// window was closed.
+	Done() <-chan struct{}
+	// Err returns why firefox has ended: ErrClosed, ErrWindowClosed, ErrKilled,
+	// an error wrapping ErrCrashed, or the context error if the context passed
+	// to Run is done. It returns nil until Done is closed.
+	Err() error
+	// Close gracefully shuts firefox down and waits until it exits. It is safe
+	// to call Close multiple times.
+	Close() error
+	// Stop is the same as Close.
+	//
+	// Deprecated: use Close.
 	Stop() error
 }
 
 type ui struct {
 	firefox *firefox
-	done    chan struct{}
 }
 
-var defaultArgs = []string{}
-
 // New returns a new HTML5 UI for the given URL, user profile directory, window
 // size and other options passed to the browser engine. If URL is an empty
 // string - a blank page is displayed. If user profile directory is an empty
-// string - a temporary directory is created and it will be removed on
-// ui.Stop().
+// string - a temporary directory is created, it is not removed when firefox
+// ends.
 // There are 3 execution modes:
 // 1. url only is provided - run firefox with the url
 // 2. url is provided with prefix "data:" - run firefox with the url encoded content data:
 // 3. url is directory with index.html as postfix - serve directory as file://
 func New(url string, customArgs, userPreferences []string) (UI, error) {
+	return NewWithOptions(url, WithArgs(customArgs...), withUserPrefs(userPreferences))
+}
+
+// NewWithOptions returns a new HTML5 UI for the given URL configured with the
+// given options. Options not set fall back to the GOFIREFOX_* environment
+// variables. See New for how the URL is handled.
+func NewWithOptions(url string, opts ...Option) (UI, error) {
 	// there is 3 execution modes:
 	// 1. url only is provided - run firefox with the url
 	// 2. url is provided with prefix "data:" - run firefox with the url (same code behaviour as 1)
@@ -52,11 +159,12 @@ func New(url string, customArgs, userPreferences []string) (UI, error) {
 		url = "file://" + url
 	}
 
-	args := customArgs
-	args = append(args, fmt.Sprintf("--new-window=%s", url))
-	args = append(args, "--kiosk")
+	config := defaultConfig()
+	for _, opt := range opts {
+		opt(config)
+	}
 
-	firefox, err := new(args, userPreferences)
+	firefox, err := new(url, config)
 	if err != nil {
 		return nil, err
 	}
@@ -64,18 +172,232 @@ func New(url string, customArgs, userPreferences []string) (UI, error) {
 	return &ui{firefox: firefox}, nil
 }
 
-func (u *ui) Stop() error {
-	// ignore err, as the chrome process might be already dead, when user close the window.
-	err := u.firefox.stop()
+// ConnectExisting returns a UI attached to the already running firefox whose
+// remote protocol listens on wsURL, e.g. "ws://127.0.0.1:9222/devtools/browser/<id>"
+// for CDP. Nothing is started: the launcher and profile options are ignored,
+// Run returns once the connection is closed, and Close only disconnects. The
+// main tab is the first tab found.
+func ConnectExisting(wsURL string, opts ...Option) (UI, error) {
+	config := defaultConfig()
+	for _, opt := range opts {
+		opt(config)
+	}
+	config.remoteURL = wsURL
+
+	firefox, err := new("", config)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	<-u.done
+
+	return &ui{firefox: firefox}, nil
+}
+
+func (u *ui) Done() <-chan struct{} {
+	return u.firefox.done
+}
+
+func (u *ui) Err() error {
+	return u.firefox.reason()
+}
+
+func (u *ui) Close() error {
+	u.firefox.close(ErrClosed)
 	return nil
 }
 
+func (u *ui) Stop() error {
+	return u.Close()
+}
+
 func (u *ui) Load(url string) error {
-	return u.firefox.load(url)
+	t := u.firefox.mainTab()
+	if t == nil {
+		return ErrConnectionClosed
+	}
+	return t.Load(url)
+}
+
+func (u *ui) LoadAndWait(ctx context.Context, url string, until LoadState) error {
+	t, err := u.firefox.waitMainTab(ctx)
+	if err != nil {
+		return err
+	}
+	return t.LoadAndWait(ctx, url, until)
+}
+
+func (u *ui) WaitForNavigation(ctx context.Context, until LoadState) error {
+	t, err := u.firefox.waitMainTab(ctx)
+	if err != nil {
+		return err
+	}
+	return t.WaitForNavigation(ctx, until)
+}
+
+func (u *ui) Eval(ctx context.Context, js string) (Value, error) {
+	t := u.firefox.mainTab()
+	if t == nil {
+		return nil, ErrConnectionClosed
+	}
+	return t.Eval(ctx, js)
+}
+
+func (u *ui) EvalInto(ctx context.Context, js string, out interface{}) error {
+	t := u.firefox.mainTab()
+	if t == nil {
+		return ErrConnectionClosed
+	}
+	return t.EvalInto(ctx, js, out)
+}
+
+func (u *ui) Screenshot(ctx context.Context, opts ScreenshotOptions) ([]byte, error) {
+	t := u.firefox.mainTab()
+	if t == nil {
+		return nil, ErrConnectionClosed
+	}
+	return t.Screenshot(ctx, opts)
+}
+
+func (u *ui) PrintToPDF(ctx context.Context, opts PDFOptions) ([]byte, error) {
+	t := u.firefox.mainTab()
+	if t == nil {
+		return nil, ErrConnectionClosed
+	}
+	return t.PrintToPDF(ctx, opts)
+}
+
+func (u *ui) Send(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	t := u.firefox.mainTab()
+	if t == nil {
+		return nil, ErrConnectionClosed
+	}
+	return t.Send(ctx, method, params)
+}
+
+func (u *ui) NewTab(url string) (Tab, error) {
+	t, err := u.firefox.newTab(context.Background(), url)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (u *ui) Tabs() []Tab {
+	tabs := []Tab{}
+	for _, t := range u.firefox.attachedTabs() {
+		tabs = append(tabs, t)
+	}
+	return tabs
+}
+
+func (u *ui) Console() <-chan ConsoleMessage {
+	return u.firefox.consoleEvents.channel()
+}
+
+func (u *ui) Exceptions() <-chan JSException {
+	return u.firefox.exceptionEvents.channel()
+}
+
+func (u *ui) DroppedEvents() (console, exceptions uint64) {
+	return u.firefox.consoleEvents.dropped.Load(), u.firefox.exceptionEvents.dropped.Load()
+}
+
+func (u *ui) IdleResets() <-chan IdleResetEvent {
+	return u.firefox.idleEvents.channel()
+}
+
+func (u *ui) On(method string, handler func(json.RawMessage)) func() {
+	return u.firefox.subscriptions.subscribe(method, handler)
+}
+
+// Subscribe is UI.On with the event params decoded into T. Events which can't
+// be decoded into T are skipped.
+func Subscribe[T any](u UI, method string, handler func(T)) (unsubscribe func()) {
+	return u.On(method, func(raw json.RawMessage) {
+		var params T
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return
+		}
+		handler(params)
+	})
+}
+
+func (u *ui) Intercept(pattern string, handler func(*InterceptedRequest), types ...ResourceType) (func(), error) {
+	re, err := globRegexp(pattern)
+	if err != nil {
+		return nil, err
+	}
+	i := &interceptor{pattern: pattern, re: re, types: types, handler: handler}
+	if err := u.firefox.intercept(i); err != nil {
+		u.firefox.removeInterceptor(i)
+		return nil, err
+	}
+	return func() { u.firefox.removeInterceptor(i) }, nil
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+func (u *ui) Bind(name string, f interface{}) error {
+	v := reflect.ValueOf(f)
+	// f must be a function
+	if v.Kind() != reflect.Func {
+		return errors.New("only functions can be bound")
+	}
+	t := v.Type()
+	if t.IsVariadic() {
+		return errors.New("variadic functions can't be bound")
+	}
+	// f must return either value and error or just error
+	switch t.NumOut() {
+	case 0, 1:
+	case 2:
+		if !t.Out(1).Implements(errorType) {
+			return errors.New("second return value must be an error")
+		}
+	default:
+		return errors.New("function may only return a value or a value+error")
+	}
+
+	return u.firefox.bind(name, func(raw []json.RawMessage) (result interface{}, err error) {
+		defer func() {
+			// a panic of the bound function is reported to JS instead of
+			// crashing the program
+			if r := recover(); r != nil {
+				result, err = nil, fmt.Errorf("function %s panicked: %v", name, r)
+			}
+		}()
+		if len(raw) != t.NumIn() {
+			return nil, fmt.Errorf("function %s expects %d arguments, got %d", name, t.NumIn(), len(raw))
+		}
+		args := []reflect.Value{}
+		for i := range raw {
+			arg := reflect.New(t.In(i))
+			if err := json.Unmarshal(raw[i], arg.Interface()); err != nil {
+				return nil, err
+			}
+			args = append(args, arg.Elem())
+		}
+		res := v.Call(args)
+		switch len(res) {
+		case 0:
+			// return nothing
+			return nil, nil
+		case 1:
+			// return value or error
+			if res[0].Type().Implements(errorType) {
+				if err, _ := res[0].Interface().(error); err != nil {
+					return nil, err
+				}
+				return nil, nil
+			}
+			return res[0].Interface(), nil
+		default:
+			// return value and error
+			if err, _ := res[1].Interface().(error); err != nil {
+				return nil, err
+			}
+			return res[0].Interface(), nil
+		}
+	})
 }
 
 func (u *ui) Run(ctx context.Context) error {
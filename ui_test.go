@@ -0,0 +1,1807 @@
+package gofirefox_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gofirefox "github.com/unikiosk/go-firefox"
+	"github.com/unikiosk/go-firefox/gofirefoxtest"
+)
+
+// start runs the UI against the fake browser and waits until the main tab is
+// attached. The UI is closed when the test ends.
+func start(t *testing.T, b *gofirefoxtest.Browser, opts ...gofirefox.Option) (gofirefox.UI, <-chan error) {
+	t.Helper()
+	ui, err := gofirefox.NewWithOptions("about:blank", append(b.Options(), opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ui, run(t, ui)
+}
+
+// run runs the UI and waits until the main tab is attached.
+func run(t *testing.T, ui gofirefox.UI) <-chan error {
+	t.Helper()
+	errc := make(chan error, 1)
+	go func() { errc <- ui.Run(context.Background()) }()
+	t.Cleanup(func() { ui.Close() })
+
+	eventually(t, "main tab was not attached", func() bool { return len(ui.Tabs()) > 0 })
+	return errc
+}
+
+// eventually waits until cond is true, and fails the test with msg if it
+// isn't within gofirefoxtest.WaitTimeout.
+func eventually(t *testing.T, msg string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(gofirefoxtest.WaitTimeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func wait(t *testing.T, errc <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-errc:
+		return err
+	case <-time.After(gofirefoxtest.WaitTimeout):
+		t.Fatal("Run has not returned")
+		return nil
+	}
+}
+
+func TestRunClose(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	ui, errc := start(t, b)
+
+	for _, method := range []string{"Page.enable", "Runtime.enable"} {
+		if call := b.WaitCall(method, nil); call.Target != b.MainTarget() {
+			t.Errorf("%s sent to %q, want %q", method, call.Target, b.MainTarget())
+		}
+	}
+
+	if err := ui.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := wait(t, errc); err != nil {
+		t.Errorf("Run() = %v, want nil", err)
+	}
+	if err := ui.Err(); !errors.Is(err, gofirefox.ErrClosed) {
+		t.Errorf("Err() = %v, want %v", err, gofirefox.ErrClosed)
+	}
+	b.WaitCall("Browser.close", nil)
+}
+
+func TestExecLauncher(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	ui, errc := start(t, b, gofirefox.WithLauncher(&gofirefox.ExecLauncher{Path: b.Executable()}))
+
+	if err := ui.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := wait(t, errc); err != nil {
+		t.Errorf("Run() = %v, want nil", err)
+	}
+	b.WaitCall("Browser.close", nil)
+}
+
+func TestExecLauncherNotStarted(t *testing.T) {
+	l := &gofirefox.ExecLauncher{Path: "firefox"}
+	if err := l.Kill(); err == nil {
+		t.Error("Kill() succeeded before Start")
+	}
+	if err := l.Terminate(); err == nil {
+		t.Error("Terminate() succeeded before Start")
+	}
+	if err := l.Wait(); err == nil {
+		t.Error("Wait() succeeded before Start")
+	}
+	if pid := l.Pid(); pid != 0 {
+		t.Errorf("Pid() = %d before Start", pid)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Start(ctx, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Start() = %v, want %v", err, context.Canceled)
+	}
+	if err := l.Kill(); err == nil {
+		t.Error("Kill() succeeded after a failed Start")
+	}
+}
+
+func TestConnectExisting(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	ui, err := gofirefox.ConnectExisting(b.URL(), gofirefox.WithProtocol(gofirefox.ProtocolCDP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	errc := run(t, ui)
+	if tabs := ui.Tabs(); tabs[0].ID() != b.MainTarget() {
+		t.Errorf("main tab = %q, want %q", tabs[0].ID(), b.MainTarget())
+	}
+
+	if err := ui.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := wait(t, errc); err != nil {
+		t.Errorf("Run() = %v, want nil", err)
+	}
+	if calls := b.Calls("Browser.close"); len(calls) != 0 {
+		t.Error("existing firefox was closed")
+	}
+}
+
+func TestWindowClosed(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	ui, errc := start(t, b)
+
+	b.Emit("Target.targetDestroyed", map[string]interface{}{"targetId": b.MainTarget()})
+	if err := wait(t, errc); err != nil {
+		t.Errorf("Run() = %v, want nil", err)
+	}
+	if err := ui.Err(); !errors.Is(err, gofirefox.ErrWindowClosed) {
+		t.Errorf("Err() = %v, want %v", err, gofirefox.ErrWindowClosed)
+	}
+}
+
+func TestCloseMainTab(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	ui, errc := start(t, b)
+
+	if err := ui.Tabs()[0].Close(); err != nil {
+		t.Fatal(err)
+	}
+	b.WaitCall("Target.closeTarget", nil)
+	if err := wait(t, errc); err != nil {
+		t.Errorf("Run() = %v, want nil", err)
+	}
+	select {
+	case <-ui.Done():
+	default:
+		t.Error("Done() is not closed")
+	}
+	if err := ui.Err(); !errors.Is(err, gofirefox.ErrWindowClosed) {
+		t.Errorf("Err() = %v, want %v", err, gofirefox.ErrWindowClosed)
+	}
+}
+
+func TestEval(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	b.Handle("Runtime.evaluate", func(call gofirefoxtest.Call) (interface{}, error) {
+		params := struct {
+			Expression string `json:"expression"`
+		}{}
+		json.Unmarshal(call.Params, &params)
+		switch params.Expression {
+		case "1 + 2":
+			return map[string]interface{}{"result": map[string]interface{}{"type": "number", "value": 3}}, nil
+		case "boom()":
+			return map[string]interface{}{
+				"result": map[string]interface{}{"type": "object"},
+				"exceptionDetails": map[string]interface{}{
+					"text":      "Uncaught",
+					"exception": map[string]interface{}{"type": "object", "description": "ReferenceError: boom is not defined"},
+				},
+			}, nil
+		}
+		return map[string]interface{}{"result": map[string]interface{}{"type": "undefined"}}, nil
+	})
+	ui, _ := start(t, b)
+	ctx := context.Background()
+
+	v, err := ui.Eval(ctx, "1 + 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Int() != 3 {
+		t.Errorf("Eval() = %s, want 3", v.Bytes())
+	}
+
+	_, err = ui.Eval(ctx, "boom()")
+	exception := &gofirefox.JSException{}
+	if !errors.As(err, &exception) {
+		t.Fatalf("Eval() error = %v, want *JSException", err)
+	}
+	if !strings.Contains(exception.Description, "boom is not defined") {
+		t.Errorf("Description = %q", exception.Description)
+	}
+
+	out := 42
+	if err := ui.EvalInto(ctx, "boom()", &out); !errors.As(err, &exception) || out != 42 {
+		t.Errorf("EvalInto() = %d, %v, want 42 untouched and *JSException", out, err)
+	}
+	if err := ui.EvalInto(ctx, "1 + 2", &out); err != nil || out != 3 {
+		t.Errorf("EvalInto() = %d, %v, want 3", out, err)
+	}
+}
+
+func TestSend(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	b.Handle("Emulation.setDeviceMetricsOverride", func(call gofirefoxtest.Call) (interface{}, error) {
+		return map[string]interface{}{}, nil
+	})
+	b.Handle("Input.dispatchKeyEvent", func(call gofirefoxtest.Call) (interface{}, error) {
+		return nil, &gofirefox.ProtocolError{Code: -32602, Message: "Invalid parameters", Data: "type: string value expected"}
+	})
+	ui, _ := start(t, b)
+	ctx := context.Background()
+
+	params := map[string]interface{}{"width": 800, "height": 600}
+	if _, err := ui.Send(ctx, "Emulation.setDeviceMetricsOverride", params); err != nil {
+		t.Fatal(err)
+	}
+	call := b.WaitCall("Emulation.setDeviceMetricsOverride", nil)
+	if call.Target != b.MainTarget() || string(call.Params) != `{"height":600,"width":800}` {
+		t.Errorf("sent %s to %q", call.Params, call.Target)
+	}
+
+	_, err := ui.Send(ctx, "Input.dispatchKeyEvent", nil)
+	perr := &gofirefox.ProtocolError{}
+	if !errors.As(err, &perr) {
+		t.Fatalf("Send() error = %v, want *ProtocolError", err)
+	}
+	if want := (gofirefox.ProtocolError{Code: -32602, Message: "Invalid parameters", Data: "type: string value expected"}); *perr != want {
+		t.Errorf("Send() error = %+v, want %+v", *perr, want)
+	}
+}
+
+// capture calls call, which sends the method to the browser, checks that it
+// returns the decoded data and returns the params the method was sent with.
+func capture(t *testing.T, b *gofirefoxtest.Browser, method, data string, call func() ([]byte, error)) map[string]interface{} {
+	t.Helper()
+	n := len(b.Calls(method))
+	got, err := call()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != data {
+		t.Errorf("%s data = %q, want %q", method, got, data)
+	}
+	calls := b.Calls(method)
+	if len(calls) != n+1 {
+		t.Fatalf("%s was sent %d times, want once", method, len(calls)-n)
+	}
+	params := map[string]interface{}{}
+	json.Unmarshal(calls[n].Params, &params)
+	return params
+}
+
+func TestScreenshot(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	b.Handle("Page.captureScreenshot", func(call gofirefoxtest.Call) (interface{}, error) {
+		return map[string]interface{}{"data": "aW1hZ2U="}, nil
+	})
+	b.Handle("Page.getLayoutMetrics", func(call gofirefoxtest.Call) (interface{}, error) {
+		return map[string]interface{}{"contentSize": map[string]interface{}{"x": 0, "y": 0, "width": 800, "height": 3000}}, nil
+	})
+	b.Handle("Runtime.evaluate", func(call gofirefoxtest.Call) (interface{}, error) {
+		if strings.Contains(string(call.Params), `#banner`) {
+			rect := map[string]interface{}{"x": 10, "y": 1200, "width": 300, "height": 50}
+			return map[string]interface{}{"result": map[string]interface{}{"type": "object", "value": rect}}, nil
+		}
+		return map[string]interface{}{"result": map[string]interface{}{"type": "object", "subtype": "null", "value": nil}}, nil
+	})
+	ui, _ := start(t, b)
+	ctx := context.Background()
+
+	screenshot := func(t *testing.T, opts gofirefox.ScreenshotOptions) map[string]interface{} {
+		t.Helper()
+		return capture(t, b, "Page.captureScreenshot", "image", func() ([]byte, error) { return ui.Screenshot(ctx, opts) })
+	}
+
+	t.Run("viewport", func(t *testing.T) {
+		params := screenshot(t, gofirefox.ScreenshotOptions{})
+		if params["format"] != "png" || params["clip"] != nil || params["quality"] != nil {
+			t.Errorf("Page.captureScreenshot(%v)", params)
+		}
+	})
+	t.Run("JPEG", func(t *testing.T) {
+		params := screenshot(t, gofirefox.ScreenshotOptions{Format: gofirefox.ImageJPEG, Quality: 80})
+		if params["format"] != "jpeg" || params["quality"] != 80.0 {
+			t.Errorf("Page.captureScreenshot(%v)", params)
+		}
+	})
+	t.Run("full page", func(t *testing.T) {
+		params := screenshot(t, gofirefox.ScreenshotOptions{FullPage: true})
+		clip := map[string]interface{}{"x": 0.0, "y": 0.0, "width": 800.0, "height": 3000.0, "scale": 1.0}
+		if !reflect.DeepEqual(params["clip"], clip) || params["captureBeyondViewport"] != true {
+			t.Errorf("Page.captureScreenshot(%v)", params)
+		}
+	})
+	t.Run("selector", func(t *testing.T) {
+		params := screenshot(t, gofirefox.ScreenshotOptions{Selector: "#banner"})
+		clip := map[string]interface{}{"x": 10.0, "y": 1200.0, "width": 300.0, "height": 50.0, "scale": 1.0}
+		if !reflect.DeepEqual(params["clip"], clip) {
+			t.Errorf("Page.captureScreenshot(%v)", params)
+		}
+	})
+	t.Run("element not found", func(t *testing.T) {
+		if _, err := ui.Screenshot(ctx, gofirefox.ScreenshotOptions{Selector: "#missing"}); !errors.Is(err, gofirefox.ErrElementNotFound) {
+			t.Errorf("Screenshot() = %v, want %v", err, gofirefox.ErrElementNotFound)
+		}
+	})
+	t.Run("unknown format", func(t *testing.T) {
+		if _, err := ui.Screenshot(ctx, gofirefox.ScreenshotOptions{Format: "gif"}); err == nil {
+			t.Error("Screenshot() succeeded with an unknown format")
+		}
+	})
+}
+
+func TestPrintToPDF(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	b.Handle("Page.printToPDF", func(call gofirefoxtest.Call) (interface{}, error) {
+		return map[string]interface{}{"data": "JVBERi0="}, nil
+	})
+	ui, _ := start(t, b, gofirefox.WithHeadless(true), gofirefox.WithKiosk(true))
+	ctx := context.Background()
+
+	args := strings.Join(b.Args(), " ")
+	if !strings.Contains(args, "--headless") || strings.Contains(args, "--kiosk") {
+		t.Errorf("started with %v, want --headless only", b.Args())
+	}
+
+	printPDF := func(t *testing.T, opts gofirefox.PDFOptions) map[string]interface{} {
+		t.Helper()
+		return capture(t, b, "Page.printToPDF", "%PDF-", func() ([]byte, error) { return ui.PrintToPDF(ctx, opts) })
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		params := printPDF(t, gofirefox.PDFOptions{})
+		// the browser defaults
+		want := map[string]interface{}{}
+		if !reflect.DeepEqual(params, want) {
+			t.Errorf("Page.printToPDF(%v), want %v", params, want)
+		}
+	})
+	t.Run("zero margins", func(t *testing.T) {
+		params := printPDF(t, gofirefox.PDFOptions{Margins: &gofirefox.PDFMargins{}})
+		for _, name := range []string{"marginTop", "marginBottom", "marginLeft", "marginRight"} {
+			if params[name] != 0.0 {
+				t.Errorf("%s = %v, want 0", name, params[name])
+			}
+		}
+	})
+	t.Run("options", func(t *testing.T) {
+		params := printPDF(t, gofirefox.PDFOptions{
+			Paper:          gofirefox.PaperA4,
+			Landscape:      true,
+			Background:     true,
+			Scale:          0.5,
+			PageRanges:     "1-2, 4",
+			FooterTemplate: `<span class="pageNumber"></span>`,
+		})
+		want := map[string]interface{}{
+			"landscape":           true,
+			"printBackground":     true,
+			"paperWidth":          8.27,
+			"paperHeight":         11.69,
+			"scale":               0.5,
+			"pageRanges":          "1-2, 4",
+			"displayHeaderFooter": true,
+			"headerTemplate":      "",
+			"footerTemplate":      `<span class="pageNumber"></span>`,
+		}
+		if !reflect.DeepEqual(params, want) {
+			t.Errorf("Page.printToPDF(%v), want %v", params, want)
+		}
+	})
+	t.Run("invalid scale", func(t *testing.T) {
+		if _, err := ui.PrintToPDF(ctx, gofirefox.PDFOptions{Scale: 3}); err == nil {
+			t.Error("PrintToPDF() succeeded with scale 3")
+		}
+	})
+}
+
+// navigate emits the events of a successful navigation of the main frame
+// until the document is parsed.
+func navigate(b *gofirefoxtest.Browser, loader, url string) {
+	target := b.MainTarget()
+	request := "request-" + loader
+	b.EmitTarget(target, "Network.requestWillBeSent", map[string]interface{}{
+		"requestId": request, "loaderId": loader, "type": "Document", "frameId": "main",
+		"request": map[string]interface{}{"url": url},
+	})
+	b.EmitTarget(target, "Network.responseReceived", map[string]interface{}{
+		"requestId": request, "loaderId": loader, "type": "Document", "frameId": "main",
+		"response": map[string]interface{}{"url": url, "status": 200},
+	})
+	b.EmitTarget(target, "Network.loadingFinished", map[string]interface{}{"requestId": request})
+	b.EmitTarget(target, "Page.frameNavigated", map[string]interface{}{
+		"frame": map[string]interface{}{"id": "main", "loaderId": loader, "url": url},
+	})
+	b.EmitTarget(target, "Page.domContentEventFired", map[string]interface{}{})
+}
+
+// pending fails the test if errc has a result.
+func pending(t *testing.T, errc <-chan error) {
+	t.Helper()
+	select {
+	case err := <-errc:
+		t.Fatalf("returned %v before the page was loaded", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestLoadAndWait(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	b.Handle("Page.getFrameTree", func(call gofirefoxtest.Call) (interface{}, error) {
+		return map[string]interface{}{"frameTree": map[string]interface{}{"frame": map[string]interface{}{"id": "main"}}}, nil
+	})
+	b.Handle("Page.navigate", func(call gofirefoxtest.Call) (interface{}, error) {
+		if strings.Contains(string(call.Params), "unknown.invalid") {
+			return map[string]interface{}{"frameId": "main", "errorText": "NS_ERROR_UNKNOWN_HOST"}, nil
+		}
+		return map[string]interface{}{"frameId": "main"}, nil
+	})
+	ui, _ := start(t, b)
+	target := b.MainTarget()
+	idleTime := gofirefox.NetworkIdleTime
+	gofirefox.NetworkIdleTime = 100 * time.Millisecond
+	t.Cleanup(func() { gofirefox.NetworkIdleTime = idleTime })
+
+	load := func(url string, until gofirefox.LoadState) <-chan error {
+		errc := make(chan error, 1)
+		go func() { errc <- ui.LoadAndWait(context.Background(), url, until) }()
+		b.WaitCall("Page.navigate", func(call gofirefoxtest.Call) bool { return strings.Contains(string(call.Params), url) })
+		return errc
+	}
+
+	t.Run("load", func(t *testing.T) {
+		errc := load("https://example.com/load", gofirefox.LoadStateLoad)
+		navigate(b, "load", "https://example.com/load")
+		pending(t, errc)
+		b.EmitTarget(target, "Page.loadEventFired", map[string]interface{}{})
+		if err := wait(t, errc); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("DOMContentLoaded", func(t *testing.T) {
+		errc := load("https://example.com/dom", gofirefox.LoadStateDOMContentLoaded)
+		navigate(b, "dom", "https://example.com/dom")
+		if err := wait(t, errc); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("network idle", func(t *testing.T) {
+		errc := load("https://example.com/idle", gofirefox.LoadStateNetworkIdle)
+		navigate(b, "idle", "https://example.com/idle")
+		b.EmitTarget(target, "Network.requestWillBeSent", map[string]interface{}{
+			"requestId": "xhr", "loaderId": "idle", "type": "XHR", "frameId": "main",
+			"request": map[string]interface{}{"url": "https://example.com/api"},
+		})
+		b.EmitTarget(target, "Page.loadEventFired", map[string]interface{}{})
+		time.Sleep(2 * gofirefox.NetworkIdleTime)
+		pending(t, errc)
+		b.EmitTarget(target, "Network.loadingFinished", map[string]interface{}{"requestId": "xhr"})
+		if err := wait(t, errc); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("HTTP error status", func(t *testing.T) {
+		errc := load("https://example.com/missing", gofirefox.LoadStateLoad)
+		b.EmitTarget(target, "Network.requestWillBeSent", map[string]interface{}{
+			"requestId": "missing", "loaderId": "missing", "type": "Document", "frameId": "main",
+			"request": map[string]interface{}{"url": "https://example.com/missing"},
+		})
+		b.EmitTarget(target, "Network.responseReceived", map[string]interface{}{
+			"requestId": "missing", "loaderId": "missing", "type": "Document", "frameId": "main",
+			"response": map[string]interface{}{"url": "https://example.com/missing", "status": 404, "statusText": "Not Found"},
+		})
+		err := wait(t, errc)
+		nerr := &gofirefox.NavigationError{}
+		if !errors.Is(err, gofirefox.ErrHTTPStatus) || !errors.As(err, &nerr) || nerr.StatusCode != 404 {
+			t.Fatalf("LoadAndWait() error = %v, want HTTP status 404", err)
+		}
+	})
+
+	t.Run("name not resolved", func(t *testing.T) {
+		err := wait(t, load("https://unknown.invalid/", gofirefox.LoadStateLoad))
+		if !errors.Is(err, gofirefox.ErrNameNotResolved) {
+			t.Fatalf("LoadAndWait() error = %v, want ErrNameNotResolved", err)
+		}
+	})
+
+	t.Run("aborted", func(t *testing.T) {
+		errc := load("https://example.com/slow", gofirefox.LoadStateLoad)
+		b.EmitTarget(target, "Network.requestWillBeSent", map[string]interface{}{
+			"requestId": "slow", "loaderId": "slow", "type": "Document", "frameId": "main",
+			"request": map[string]interface{}{"url": "https://example.com/slow"},
+		})
+		b.EmitTarget(target, "Network.loadingFailed", map[string]interface{}{
+			"requestId": "slow", "type": "Document", "errorText": "NS_BINDING_ABORTED", "canceled": true,
+		})
+		if err := wait(t, errc); !errors.Is(err, gofirefox.ErrNavigationAborted) {
+			t.Fatalf("LoadAndWait() error = %v, want ErrNavigationAborted", err)
+		}
+	})
+
+	t.Run("frame", func(t *testing.T) {
+		errc := load("https://example.com/frames", gofirefox.LoadStateLoad)
+		navigate(b, "frames", "https://example.com/frames")
+		// documents of frames don't fail the page
+		b.EmitTarget(target, "Network.requestWillBeSent", map[string]interface{}{
+			"requestId": "frame", "loaderId": "frame", "type": "Document", "frameId": "child",
+			"request": map[string]interface{}{"url": "https://example.com/frame"},
+		})
+		b.EmitTarget(target, "Network.responseReceived", map[string]interface{}{
+			"requestId": "frame", "loaderId": "frame", "type": "Document", "frameId": "child",
+			"response": map[string]interface{}{"url": "https://example.com/frame", "status": 500},
+		})
+		b.EmitTarget(target, "Page.loadEventFired", map[string]interface{}{})
+		if err := wait(t, errc); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestLoadAndWaitStarting(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	ui, err := gofirefox.NewWithOptions("about:blank", b.Options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// called before firefox has started, like right after go ui.Run(ctx)
+	loaded := make(chan error, 1)
+	go func() {
+		loaded <- ui.LoadAndWait(context.Background(), "https://example.com/", gofirefox.LoadStateDOMContentLoaded)
+	}()
+	pending(t, loaded)
+	errc := run(t, ui)
+	b.WaitCall("Page.navigate", func(call gofirefoxtest.Call) bool { return strings.Contains(string(call.Params), "example.com") })
+	navigate(b, "start", "https://example.com/")
+	if err := wait(t, loaded); err != nil {
+		t.Fatal(err)
+	}
+
+	ui.Close()
+	wait(t, errc)
+	if err := ui.LoadAndWait(context.Background(), "https://example.com/", gofirefox.LoadStateLoad); err != gofirefox.ErrConnectionClosed {
+		t.Errorf("LoadAndWait() error = %v after Close, want %v", err, gofirefox.ErrConnectionClosed)
+	}
+}
+
+func TestWaitForNavigation(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	ui, _ := start(t, b)
+
+	errc := make(chan error, 1)
+	go func() { errc <- ui.WaitForNavigation(context.Background(), gofirefox.LoadStateLoad) }()
+	pending(t, errc)
+	// navigated by the page
+	navigate(b, "link", "https://example.com/next")
+	pending(t, errc)
+	b.EmitTarget(b.MainTarget(), "Page.loadEventFired", map[string]interface{}{})
+	if err := wait(t, errc); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := ui.WaitForNavigation(ctx, gofirefox.LoadStateLoad); err != context.DeadlineExceeded {
+		t.Errorf("WaitForNavigation() error = %v without navigation, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestIntercept(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	ui, _ := start(t, b)
+	target := b.MainTarget()
+
+	removeAPI, err := ui.Intercept("https://api.example.com/*", func(r *gofirefox.InterceptedRequest) {
+		r.Fulfill(503, map[string]string{"Content-Type": "application/json"}, []byte(`{"offline":true}`))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ui.Intercept("*.png", func(r *gofirefox.InterceptedRequest) {
+		r.Fail()
+	}, gofirefox.ResourceImage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ui.Intercept("https://example.com/?", func(r *gofirefox.InterceptedRequest) {
+		r.Headers["Authorization"] = "Bearer token"
+		r.ContinueWithHeaders(r.Headers)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enable := b.WaitCall("Fetch.enable", func(call gofirefoxtest.Call) bool {
+		return strings.Contains(string(call.Params), "?")
+	})
+	if want := `{"patterns":[{"urlPattern":"*.png"},{"urlPattern":"https://api.example.com/*"},{"urlPattern":"https://example.com/?"}]}`; string(enable.Params) != want {
+		t.Errorf("Fetch.enable params = %s, want %s", enable.Params, want)
+	}
+
+	paused := func(id, url string, resourceType gofirefox.ResourceType) {
+		b.EmitTarget(target, "Fetch.requestPaused", map[string]interface{}{
+			"requestId": id, "frameId": "main", "resourceType": resourceType,
+			"request": map[string]interface{}{"url": url, "method": "GET", "headers": map[string]interface{}{"Accept": "*/*"}},
+		})
+	}
+	requestID := func(id string) func(gofirefoxtest.Call) bool {
+		return func(call gofirefoxtest.Call) bool {
+			return strings.Contains(string(call.Params), `"requestId":"`+id+`"`)
+		}
+	}
+
+	paused("api", "https://api.example.com/status", gofirefox.ResourceXHR)
+	call := b.WaitCall("Fetch.fulfillRequest", requestID("api"))
+	if want := `{"requestId":"api","responseCode":503,"responseHeaders":[{"name":"Content-Type","value":"application/json"}],"body":"eyJvZmZsaW5lIjp0cnVlfQ=="}`; string(call.Params) != want {
+		t.Errorf("Fetch.fulfillRequest params = %s, want %s", call.Params, want)
+	}
+
+	paused("image", "https://example.com/logo.png", gofirefox.ResourceImage)
+	b.WaitCall("Fetch.failRequest", requestID("image"))
+
+	// not an image
+	paused("download", "https://example.com/logo.png", gofirefox.ResourceOther)
+	call = b.WaitCall("Fetch.continueRequest", requestID("download"))
+	if want := `{"requestId":"download"}`; string(call.Params) != want {
+		t.Errorf("Fetch.continueRequest params = %s, want %s", call.Params, want)
+	}
+
+	paused("page", "https://example.com/a", gofirefox.ResourceDocument)
+	call = b.WaitCall("Fetch.continueRequest", requestID("page"))
+	if want := `{"requestId":"page","headers":[{"name":"Accept","value":"*/*"},{"name":"Authorization","value":"Bearer token"}]}`; string(call.Params) != want {
+		t.Errorf("Fetch.continueRequest params = %s, want %s", call.Params, want)
+	}
+
+	removeAPI()
+	b.WaitCall("Fetch.enable", func(call gofirefoxtest.Call) bool {
+		return !strings.Contains(string(call.Params), "api.example.com")
+	})
+	paused("api2", "https://api.example.com/status", gofirefox.ResourceXHR)
+	b.WaitCall("Fetch.continueRequest", requestID("api2"))
+}
+
+func TestNavigationPolicy(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	blocked := make(chan gofirefox.BlockedNavigation, 10)
+	ui, _ := start(t, b, gofirefox.WithNavigationPolicy(gofirefox.NavigationPolicy{
+		Allow:     []string{"example.com", "https://docs.example.org/*"},
+		Deny:      []string{"https://example.com/admin*"},
+		Popups:    gofirefox.PopupBlock,
+		OnBlocked: func(n gofirefox.BlockedNavigation) { blocked <- n },
+	}))
+	main := b.MainTarget()
+	navigated := func(target, url string) {
+		b.Emit("Target.targetInfoChanged", map[string]interface{}{
+			"targetInfo": map[string]interface{}{"targetId": target, "type": "page", "url": url},
+		})
+	}
+	navigatedTo := func(target, url string) func(gofirefoxtest.Call) bool {
+		return func(call gofirefoxtest.Call) bool {
+			return call.Target == target && strings.Contains(string(call.Params), `"url":"`+url+`"`)
+		}
+	}
+	wantBlocked := func(want gofirefox.BlockedNavigation) {
+		t.Helper()
+		select {
+		case n := <-blocked:
+			if n != want {
+				t.Errorf("blocked %+v, want %+v", n, want)
+			}
+		case <-time.After(gofirefoxtest.WaitTimeout):
+			t.Fatalf("%s was not blocked", want.URL)
+		}
+	}
+
+	navigated(main, "https://docs.example.org/guide")
+	// blocked as soon as the navigation starts
+	b.EmitTarget(main, "Network.requestWillBeSent", map[string]interface{}{
+		"requestId": "evil", "loaderId": "evil", "type": "Document", "frameId": "main",
+		"request": map[string]interface{}{"url": "https://evil.com/"},
+	})
+	wantBlocked(gofirefox.BlockedNavigation{Tab: main, URL: "https://evil.com/"})
+	b.WaitCall("Page.navigate", navigatedTo(main, "https://docs.example.org/guide"))
+
+	navigated(main, "https://example.com/shop")
+	navigated(main, "https://example.com/admin/users")
+	wantBlocked(gofirefox.BlockedNavigation{Tab: main, URL: "https://example.com/admin/users"})
+	b.WaitCall("Page.navigate", navigatedTo(main, "https://example.com/shop"))
+
+	popup := b.OpenPopup(main, "about:blank")
+	wantBlocked(gofirefox.BlockedNavigation{Tab: popup, URL: "about:blank", Popup: true})
+	b.WaitCall("Target.closeTarget", func(call gofirefoxtest.Call) bool {
+		return strings.Contains(string(call.Params), popup)
+	})
+	for _, tab := range ui.Tabs() {
+		if tab.ID() == popup {
+			t.Error("blocked popup was attached")
+		}
+	}
+	if calls := b.Calls("Page.navigate"); len(calls) != 2 {
+		t.Errorf("navigated %d times, want 2", len(calls))
+	}
+}
+
+func TestNavigationPolicyRedirect(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	blocked := make(chan gofirefox.BlockedNavigation, 10)
+	start(t, b, gofirefox.WithNavigationPolicy(gofirefox.NavigationPolicy{
+		Allow:       []string{"*.example.com"},
+		OnViolation: gofirefox.ViolationRedirectHome,
+		HomeURL:     "https://kiosk.example.com/",
+		Popups:      gofirefox.PopupSameTab,
+		OnBlocked:   func(n gofirefox.BlockedNavigation) { blocked <- n },
+	}))
+	main := b.MainTarget()
+
+	b.Emit("Target.targetInfoChanged", map[string]interface{}{
+		"targetInfo": map[string]interface{}{"targetId": main, "type": "page", "url": "https://example.org/"},
+	})
+	call := b.WaitCall("Page.navigate", nil)
+	if call.Target != main || string(call.Params) != `{"url":"https://kiosk.example.com/"}` {
+		t.Errorf("navigated %s to %s, want home", call.Target, call.Params)
+	}
+	if n := <-blocked; n.URL != "https://example.org/" {
+		t.Errorf("blocked %s, want https://example.org/", n.URL)
+	}
+
+	// target=_blank link
+	popup := b.OpenPopup(main, "about:blank")
+	b.Emit("Target.targetInfoChanged", map[string]interface{}{
+		"targetInfo": map[string]interface{}{"targetId": popup, "type": "page", "url": "https://help.example.com/", "openerId": main},
+	})
+	b.WaitCall("Target.closeTarget", func(call gofirefoxtest.Call) bool {
+		return strings.Contains(string(call.Params), popup)
+	})
+	b.WaitCall("Page.navigate", func(call gofirefoxtest.Call) bool {
+		return call.Target == main && string(call.Params) == `{"url":"https://help.example.com/"}`
+	})
+}
+
+func TestNavigationPolicyIntercept(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	b.Handle("Page.getFrameTree", func(call gofirefoxtest.Call) (interface{}, error) {
+		return map[string]interface{}{"frameTree": map[string]interface{}{"frame": map[string]interface{}{"id": "main"}}}, nil
+	})
+	blocked := make(chan gofirefox.BlockedNavigation, 10)
+	start(t, b, gofirefox.WithNavigationPolicy(gofirefox.NavigationPolicy{
+		Allow:     []string{"example.com"},
+		OnBlocked: func(n gofirefox.BlockedNavigation) { blocked <- n },
+	}))
+	main := b.MainTarget()
+
+	enable := b.WaitCall("Fetch.enable", nil)
+	if want := `{"patterns":[{"urlPattern":"*","resourceType":"Document"}]}`; string(enable.Params) != want {
+		t.Errorf("Fetch.enable params = %s, want %s", enable.Params, want)
+	}
+	b.Emit("Target.targetInfoChanged", map[string]interface{}{
+		"targetInfo": map[string]interface{}{"targetId": main, "type": "page", "url": "https://example.com/"},
+	})
+	paused := func(id, frame, url string) {
+		b.EmitTarget(main, "Fetch.requestPaused", map[string]interface{}{
+			"requestId": id, "frameId": frame, "resourceType": "Document",
+			"request": map[string]interface{}{"url": url, "method": "GET", "headers": map[string]interface{}{}},
+		})
+	}
+	requestID := func(id string) func(gofirefoxtest.Call) bool {
+		return func(call gofirefoxtest.Call) bool {
+			return strings.Contains(string(call.Params), `"requestId":"`+id+`"`)
+		}
+	}
+
+	// blocked before the page is loaded
+	paused("evil", "main", "https://evil.com/")
+	b.WaitCall("Fetch.failRequest", requestID("evil"))
+	if n := <-blocked; n.URL != "https://evil.com/" {
+		t.Errorf("blocked %s, want https://evil.com/", n.URL)
+	}
+	call := b.WaitCall("Page.navigate", nil)
+	if string(call.Params) != `{"url":"https://example.com/"}` {
+		t.Errorf("navigated to %s, want the allowed URL", call.Params)
+	}
+
+	// frames are not tabs
+	paused("frame", "ad", "https://ads.example.org/")
+	b.WaitCall("Fetch.continueRequest", requestID("frame"))
+	paused("allowed", "main", "https://example.com/next")
+	b.WaitCall("Fetch.continueRequest", requestID("allowed"))
+}
+
+func TestNavigationPolicyRedirectLoop(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	start(t, b, gofirefox.WithNavigationPolicy(gofirefox.NavigationPolicy{
+		Allow:   []string{"example.com"},
+		HomeURL: "https://example.com/home",
+	}))
+	main := b.MainTarget()
+	navigated := func(url string) {
+		b.Emit("Target.targetInfoChanged", map[string]interface{}{
+			"targetInfo": map[string]interface{}{"targetId": main, "type": "page", "url": url},
+		})
+	}
+	wantNavigate := func(n int, url string) {
+		t.Helper()
+		eventually(t, url+" was not navigated to", func() bool { return len(b.Calls("Page.navigate")) >= n })
+		calls := b.Calls("Page.navigate")
+		if len(calls) != n || string(calls[n-1].Params) != `{"url":"`+url+`"}` {
+			t.Fatalf("navigated %d times, last to %s, want %d times, last to %s", len(calls), calls[len(calls)-1].Params, n, url)
+		}
+	}
+
+	navigated("https://example.com/a")
+	navigated("https://evil.com/")
+	wantNavigate(1, "https://example.com/a")
+	// the allowed URL and then the home URL redirect to the blocked URL
+	navigated("https://example.com/a")
+	navigated("https://evil.com/")
+	wantNavigate(2, "https://example.com/home")
+	navigated("https://example.com/home")
+	navigated("https://evil.com/")
+	wantNavigate(3, "about:blank")
+	navigated("about:blank")
+
+	// navigated by the user
+	navigated("https://example.com/b")
+	navigated("https://evil.com/")
+	wantNavigate(4, "https://example.com/b")
+}
+
+func TestIdleReset(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	ui, errc := start(t, b, gofirefox.WithIdleReset(100*time.Millisecond, "https://example.com/home"))
+	resets := ui.IdleResets()
+
+	b.WaitCall("Page.addScriptToEvaluateOnNewDocument", func(call gofirefoxtest.Call) bool {
+		return strings.Contains(string(call.Params), "__gofirefox_input__")
+	})
+	tab, err := ui.NewTab("https://example.com/other")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{"name": "__gofirefox_input__", "seq": 0, "args": []int{}})
+	b.EmitTarget(b.MainTarget(), "Runtime.consoleAPICalled", map[string]interface{}{
+		"type":               "debug",
+		"executionContextId": 7,
+		"args": []map[string]interface{}{
+			{"type": "string", "value": "__gofirefox_binding__"},
+			{"type": "string", "value": string(payload)},
+		},
+	})
+
+	select {
+	case event := <-resets:
+		if event.Err != nil || event.HomeURL != "https://example.com/home" || event.LastInput.IsZero() {
+			t.Errorf("IdleResetEvent = %+v", event)
+		}
+	case <-time.After(gofirefoxtest.WaitTimeout):
+		t.Fatal("session was not reset")
+	}
+	for _, method := range []string{"Network.clearBrowserCookies", "Network.clearBrowserCache"} {
+		if len(b.Calls(method)) != 1 {
+			t.Errorf("%s was not called", method)
+		}
+	}
+	// the storage of the open pages
+	for _, cleared := range []string{b.MainTarget(), tab.ID()} {
+		b.WaitCall("Runtime.evaluate", func(call gofirefoxtest.Call) bool {
+			return call.Target == cleared && strings.Contains(string(call.Params), "indexedDB.deleteDatabase")
+		})
+	}
+
+	// the main tab is replaced with a new one without back history
+	for _, closed := range []string{b.MainTarget(), tab.ID()} {
+		b.WaitCall("Target.closeTarget", func(call gofirefoxtest.Call) bool {
+			return strings.Contains(string(call.Params), `"`+closed+`"`)
+		})
+	}
+	eventually(t, "old tabs were not closed", func() bool { return len(ui.Tabs()) == 1 })
+	main := ui.Tabs()[0].ID()
+	if main == b.MainTarget() || main == tab.ID() {
+		t.Errorf("main tab %q was not replaced", main)
+	}
+	call := b.WaitCall("Page.navigate", nil)
+	if call.Target != main || !strings.Contains(string(call.Params), "https://example.com/home") {
+		t.Errorf("Page.navigate(%s) sent to %q, want the new main tab %q", call.Params, call.Target, main)
+	}
+	// closing the old main tab does not end firefox
+	pending(t, errc)
+
+	// no reset without input since the last one
+	time.Sleep(300 * time.Millisecond)
+	if calls := b.Calls("Page.navigate"); len(calls) != 1 {
+		t.Errorf("session was reset %d times", len(calls))
+	}
+}
+
+func TestBind(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	ui, _ := start(t, b)
+
+	if err := ui.Bind("add", func(a, b int) int { return a + b }); err != nil {
+		t.Fatal(err)
+	}
+	b.WaitCall("Page.addScriptToEvaluateOnNewDocument", nil)
+
+	payload, _ := json.Marshal(map[string]interface{}{"name": "add", "seq": 1, "args": []int{1, 2}})
+	b.EmitTarget(b.MainTarget(), "Runtime.consoleAPICalled", map[string]interface{}{
+		"type":               "debug",
+		"executionContextId": 7,
+		"args": []map[string]interface{}{
+			{"type": "string", "value": "__gofirefox_binding__"},
+			{"type": "string", "value": string(payload)},
+		},
+	})
+	b.WaitCall("Runtime.evaluate", func(call gofirefoxtest.Call) bool {
+		params := struct {
+			Expression string `json:"expression"`
+			ContextID  int    `json:"contextId"`
+		}{}
+		json.Unmarshal(call.Params, &params)
+		return params.ContextID == 7 && strings.Contains(params.Expression, "get(1)(3)")
+	})
+}
+
+func TestBindErrors(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	ui, _ := start(t, b)
+
+	if err := ui.Bind("fail", func() (int, error) { return 0, errors.New("failed") }); err != nil {
+		t.Fatal(err)
+	}
+	if err := ui.Bind("boom", func() int { panic("boom") }); err != nil {
+		t.Fatal(err)
+	}
+	if err := ui.Bind("add", func(a, b int) int { return a + b }); err != nil {
+		t.Fatal(err)
+	}
+	call := func(name string, seq int, args ...int) {
+		payload, _ := json.Marshal(map[string]interface{}{"name": name, "seq": seq, "args": args})
+		b.EmitTarget(b.MainTarget(), "Runtime.consoleAPICalled", map[string]interface{}{
+			"type":               "debug",
+			"executionContextId": 7,
+			"args": []map[string]interface{}{
+				{"type": "string", "value": "__gofirefox_binding__"},
+				{"type": "string", "value": string(payload)},
+			},
+		})
+	}
+	rejected := func(name string, seq int, msg string) {
+		t.Helper()
+		want := fmt.Sprintf(`window[%q]['errors'].get(%d)(%q)`, name, seq, msg)
+		b.WaitCall("Runtime.evaluate", func(call gofirefoxtest.Call) bool {
+			params := struct {
+				Expression string `json:"expression"`
+			}{}
+			json.Unmarshal(call.Params, &params)
+			return strings.Contains(params.Expression, want)
+		})
+	}
+
+	call("fail", 1)
+	rejected("fail", 1, "failed")
+	call("boom", 1)
+	rejected("boom", 1, "function boom panicked: boom")
+	call("add", 1, 1)
+	rejected("add", 1, "function add expects 2 arguments, got 1")
+
+	// calls of unknown bindings are ignored
+	call("missing", 1)
+	call("add", 2, 1, 2)
+	b.WaitCall("Runtime.evaluate", func(call gofirefoxtest.Call) bool {
+		return strings.Contains(string(call.Params), "get(2)(3)")
+	})
+	for _, call := range b.Calls("Runtime.evaluate") {
+		if strings.Contains(string(call.Params), "missing") {
+			t.Errorf("unknown binding was answered: %s", call.Params)
+		}
+	}
+}
+
+func TestConsoleLog(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	logs := make(chan map[string]interface{}, 100)
+	start(t, b, gofirefox.WithSlog(slog.New(slog.NewJSONHandler(writerFunc(func(p []byte) {
+		entry := map[string]interface{}{}
+		json.Unmarshal(p, &entry)
+		select {
+		case logs <- entry:
+		default:
+		}
+	}), nil))))
+
+	b.EmitTarget(b.MainTarget(), "Runtime.consoleAPICalled", map[string]interface{}{
+		"type":               "error",
+		"executionContextId": 1,
+		"args":               []map[string]interface{}{{"type": "string", "value": "oops"}},
+	})
+	b.EmitTarget(b.MainTarget(), "Runtime.exceptionThrown", map[string]interface{}{
+		"exceptionDetails": map[string]interface{}{
+			"text":      "Uncaught",
+			"exception": map[string]interface{}{"type": "object", "description": "Error: boom"},
+		},
+	})
+	want := map[string]bool{"console": true, "exception": true}
+	for len(want) > 0 {
+		select {
+		case entry := <-logs:
+			source, _ := entry["source"].(string)
+			if !want[source] {
+				continue
+			}
+			delete(want, source)
+			if entry["level"] != "ERROR" || entry["target"] != b.MainTarget() {
+				t.Errorf("%s log = %v", source, entry)
+			}
+		case <-time.After(gofirefoxtest.WaitTimeout):
+			t.Fatalf("%v were not logged", want)
+		}
+	}
+}
+
+func TestLogger(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	lines := make(chan string, 100)
+	start(t, b, gofirefox.WithSlog(nil), gofirefox.WithLogger(log.New(writerFunc(func(p []byte) {
+		select {
+		case lines <- string(p):
+		default:
+		}
+	}), "kiosk: ", 0)))
+
+	b.EmitTarget(b.MainTarget(), "Runtime.consoleAPICalled", map[string]interface{}{
+		"type":               "error",
+		"executionContextId": 1,
+		"args":               []map[string]interface{}{{"type": "string", "value": "oops"}},
+	})
+	for {
+		select {
+		case line := <-lines:
+			if strings.Contains(line, "oops") {
+				if !strings.HasPrefix(line, "kiosk: level=ERROR ") || strings.Contains(line, "time=") {
+					t.Errorf("logged %q, want a text line without time", line)
+				}
+				return
+			}
+		case <-time.After(gofirefoxtest.WaitTimeout):
+			t.Fatal("console message was not logged")
+		}
+	}
+}
+
+func TestLoggerPrecedence(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	var logged, slogged atomic.Bool
+	logger := log.New(writerFunc(func(p []byte) { logged.Store(true) }), "", 0)
+	slogger := slog.New(slog.NewTextHandler(writerFunc(func(p []byte) {
+		if strings.Contains(string(p), "oops") {
+			slogged.Store(true)
+		}
+	}), nil))
+	// Slog wins, whatever the order of the options
+	start(t, b, gofirefox.WithSlog(slogger), gofirefox.WithLogger(logger))
+
+	b.EmitTarget(b.MainTarget(), "Runtime.consoleAPICalled", map[string]interface{}{
+		"type":               "error",
+		"executionContextId": 1,
+		"args":               []map[string]interface{}{{"type": "string", "value": "oops"}},
+	})
+	eventually(t, "console message was not logged to Slog", slogged.Load)
+	if logged.Load() {
+		t.Error("logged to Logger too")
+	}
+}
+
+func TestConsoleEvents(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	ui, _ := start(t, b, gofirefox.WithEventBuffer(1))
+	console, exceptions := ui.Console(), ui.Exceptions()
+
+	logged := func(value string) {
+		b.EmitTarget(b.MainTarget(), "Runtime.consoleAPICalled", map[string]interface{}{
+			"type":               "warning",
+			"executionContextId": 1,
+			"timestamp":          1700000000123.0,
+			"args":               []map[string]interface{}{{"type": "string", "value": value}, {"type": "number", "value": 42}},
+			"stackTrace": map[string]interface{}{
+				"callFrames": []map[string]interface{}{{"url": "https://example.com/app.js", "lineNumber": 9, "columnNumber": 4}},
+			},
+		})
+	}
+	logged("first")
+	select {
+	case msg := <-console:
+		want := gofirefox.ConsoleMessage{
+			Type: "warning", Text: "first 42", Tab: b.MainTarget(),
+			URL: "https://example.com/app.js", LineNumber: 9, ColumnNumber: 4,
+			Timestamp: time.UnixMilli(1700000000123),
+		}
+		msg.Args = nil
+		if !reflect.DeepEqual(msg, want) {
+			t.Errorf("console message = %+v, want %+v", msg, want)
+		}
+	case <-time.After(gofirefoxtest.WaitTimeout):
+		t.Fatal("console message was not received")
+	}
+
+	b.EmitTarget(b.MainTarget(), "Runtime.exceptionThrown", map[string]interface{}{
+		"exceptionDetails": map[string]interface{}{
+			"text":      "Uncaught",
+			"exception": map[string]interface{}{"type": "object", "description": "Error: boom"},
+		},
+	})
+	select {
+	case e := <-exceptions:
+		if e.Description != "Error: boom" {
+			t.Errorf("exception = %+v", e)
+		}
+	case <-time.After(gofirefoxtest.WaitTimeout):
+		t.Fatal("exception was not received")
+	}
+
+	// the buffer holds a single message, the others are dropped
+	for _, value := range []string{"second", "third", "fourth"} {
+		logged(value)
+	}
+	eventually(t, "console messages were not dropped", func() bool {
+		dropped, _ := ui.DroppedEvents()
+		return dropped == 2
+	})
+	if msg := <-console; msg.Text != "second 42" {
+		t.Errorf("buffered message = %q, want %q", msg.Text, "second 42")
+	}
+}
+
+func TestOn(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	ui, _ := start(t, b)
+
+	type response struct {
+		RequestID string `json:"requestId"`
+	}
+	responses := make(chan response, 10)
+	unblock := make(chan struct{})
+	unsubscribe := gofirefox.Subscribe(ui, "Network.responseReceived", func(r response) {
+		<-unblock
+		responses <- r
+	})
+	defer unsubscribe()
+
+	for _, id := range []string{"1", "2"} {
+		b.EmitTarget(b.MainTarget(), "Network.responseReceived", map[string]interface{}{"requestId": id})
+	}
+	// the blocked handler doesn't stall the commands
+	if _, err := ui.Eval(context.Background(), "1"); err != nil {
+		t.Fatal(err)
+	}
+	close(unblock)
+	for _, want := range []string{"1", "2"} {
+		select {
+		case r := <-responses:
+			if r.RequestID != want {
+				t.Errorf("requestId = %q, want %q", r.RequestID, want)
+			}
+		case <-time.After(gofirefoxtest.WaitTimeout):
+			t.Fatal("event was not handled")
+		}
+	}
+
+	unsubscribe()
+	b.EmitTarget(b.MainTarget(), "Network.responseReceived", map[string]interface{}{"requestId": "3"})
+	ui.Eval(context.Background(), "1")
+	select {
+	case r := <-responses:
+		t.Errorf("event %q handled after unsubscribe", r.RequestID)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// writerFunc is an io.Writer calling the function with each write.
+type writerFunc func(p []byte)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	f(p)
+	return len(p), nil
+}
+
+func TestTabs(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	ui, errc := start(t, b)
+
+	tab, err := ui.NewTab("https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.WaitCall("Runtime.enable", func(call gofirefoxtest.Call) bool { return call.Target == tab.ID() })
+	if tabs := ui.Tabs(); len(tabs) != 2 || tabs[0].ID() != b.MainTarget() || tabs[1].ID() != tab.ID() {
+		t.Errorf("Tabs() = %v", tabs)
+	}
+
+	if err := tab.Load("https://example.org"); err != nil {
+		t.Fatal(err)
+	}
+	if call := b.WaitCall("Page.navigate", nil); call.Target != tab.ID() {
+		t.Errorf("Page.navigate sent to %q, want %q", call.Target, tab.ID())
+	}
+
+	if err := tab.Close(); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "tab was not closed", func() bool { return len(ui.Tabs()) == 1 })
+	if err := tab.Load("about:blank"); !errors.Is(err, gofirefox.ErrTabClosed) {
+		t.Errorf("Load() on closed tab = %v, want %v", err, gofirefox.ErrTabClosed)
+	}
+
+	select {
+	case err := <-errc:
+		t.Fatalf("Run() = %v after closing a tab", err)
+	default:
+	}
+}
+
+func TestTabDestroyed(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	ui, errc := start(t, b)
+
+	tab, err := ui.NewTab("https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Emit("Target.targetDestroyed", map[string]interface{}{"targetId": tab.ID()})
+	eventually(t, "tab was not removed", func() bool { return len(ui.Tabs()) == 1 })
+
+	ctx := context.Background()
+	for name, call := range map[string]func() error{
+		"Load":     func() error { return tab.Load("about:blank") },
+		"Eval":     func() error { _, err := tab.Eval(ctx, "1"); return err },
+		"EvalInto": func() error { return tab.EvalInto(ctx, "1", new(int)) },
+		"Send":     func() error { _, err := tab.Send(ctx, "Page.reload", nil); return err },
+		"Activate": tab.Activate,
+		"Close":    tab.Close,
+		"Screenshot": func() error {
+			_, err := tab.Screenshot(ctx, gofirefox.ScreenshotOptions{})
+			return err
+		},
+		"PrintToPDF": func() error { _, err := tab.PrintToPDF(ctx, gofirefox.PDFOptions{}); return err },
+		"LoadAndWait": func() error {
+			return tab.LoadAndWait(ctx, "about:blank", gofirefox.LoadStateLoad)
+		},
+		"WaitForNavigation": func() error { return tab.WaitForNavigation(ctx, gofirefox.LoadStateLoad) },
+	} {
+		if err := call(); !errors.Is(err, gofirefox.ErrTabClosed) {
+			t.Errorf("%s() = %v, want %v", name, err, gofirefox.ErrTabClosed)
+		}
+	}
+
+	select {
+	case err := <-errc:
+		t.Fatalf("Run() = %v after a tab was destroyed", err)
+	default:
+	}
+}
+
+func TestRestart(t *testing.T) {
+	b := gofirefoxtest.New(t, "about:blank")
+	restarts := make(chan gofirefox.RestartEvent, 10)
+	ui, errc := start(t, b, gofirefox.WithRestartPolicy(gofirefox.RestartPolicy{
+		InitialBackoff: time.Millisecond,
+		OnRestart:      func(e gofirefox.RestartEvent) { restarts <- e },
+	}))
+	if err := ui.Bind("add", func(a, b int) int { return a + b }); err != nil {
+		t.Fatal(err)
+	}
+	b.Emit("Target.targetInfoChanged", map[string]interface{}{
+		"targetInfo": map[string]interface{}{"targetId": b.MainTarget(), "type": "page", "url": "https://example.com/last"},
+	})
+	b.WaitCall("Page.addScriptToEvaluateOnNewDocument", nil)
+	eventually(t, "URL change was not handled", func() bool { return ui.Tabs()[0].URL() == "https://example.com/last" })
+
+	b.Crash()
+	select {
+	case e := <-restarts:
+		if e.Restart != 1 || !errors.Is(e.Cause, gofirefox.ErrCrashed) {
+			t.Errorf("restart event = %+v", e)
+		}
+	case <-time.After(gofirefoxtest.WaitTimeout):
+		t.Fatal("firefox was not restarted")
+	}
+
+	// bindings are reinstalled after the restart
+	eventually(t, "bindings were not reinstalled", func() bool {
+		return len(b.Calls("Page.addScriptToEvaluateOnNewDocument")) == 2
+	})
+	found := false
+	for _, arg := range b.Args() {
+		found = found || arg == "--new-window=https://example.com/last"
+	}
+	if !found {
+		t.Errorf("restarted with %v, want the last URL", b.Args())
+	}
+
+	if err := ui.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := wait(t, errc); err != nil {
+		t.Errorf("Run() = %v, want nil", err)
+	}
+}
+
+func TestBiDiEval(t *testing.T) {
+	tests := []struct {
+		name   string
+		result map[string]interface{}
+		want   string
+	}{
+		{name: "undefined", result: map[string]interface{}{"type": "undefined"}, want: ""},
+		{name: "null", result: map[string]interface{}{"type": "null"}, want: "null"},
+		{name: "string", result: map[string]interface{}{"type": "string", "value": "hi"}, want: `"hi"`},
+		{name: "number", result: map[string]interface{}{"type": "number", "value": 1.5}, want: "1.5"},
+		{name: "NaN", result: map[string]interface{}{"type": "number", "value": "NaN"}, want: `"NaN"`},
+		{name: "bigint", result: map[string]interface{}{"type": "bigint", "value": "12"}, want: `"12"`},
+		{name: "regexp", result: map[string]interface{}{"type": "regexp", "value": map[string]interface{}{"pattern": "a+", "flags": "g"}}, want: `"/a+/g"`},
+		{name: "array", result: map[string]interface{}{"type": "array", "value": []interface{}{
+			map[string]interface{}{"type": "number", "value": 1},
+			map[string]interface{}{"type": "node"},
+		}}, want: "[1,null]"},
+		{name: "object", result: map[string]interface{}{"type": "object", "value": []interface{}{
+			[]interface{}{"a", map[string]interface{}{"type": "boolean", "value": true}},
+			[]interface{}{"b", map[string]interface{}{"type": "undefined"}},
+		}}, want: `{"a":true}`},
+		{name: "map", result: map[string]interface{}{"type": "map", "value": []interface{}{
+			[]interface{}{map[string]interface{}{"type": "number", "value": 1}, map[string]interface{}{"type": "string", "value": "one"}},
+		}}, want: `{"1":"one"}`},
+		{name: "node", result: map[string]interface{}{"type": "node", "sharedId": "n1"}, want: `"node"`},
+	}
+	b := gofirefoxtest.NewBiDi(t, "about:blank")
+	b.Handle("script.evaluate", func(call gofirefoxtest.Call) (interface{}, error) {
+		params := struct {
+			Expression string `json:"expression"`
+		}{}
+		json.Unmarshal(call.Params, &params)
+		for _, test := range tests {
+			if test.name == params.Expression {
+				return map[string]interface{}{"type": "success", "result": test.result, "realm": "realm-1"}, nil
+			}
+		}
+		if params.Expression == "throw" {
+			return map[string]interface{}{"type": "exception", "realm": "realm-1", "exceptionDetails": map[string]interface{}{
+				"text": "Error: boom", "lineNumber": 1, "columnNumber": 2,
+				"exception":  map[string]interface{}{"type": "error"},
+				"stackTrace": map[string]interface{}{"callFrames": []interface{}{map[string]interface{}{"url": "https://example.com/app.js", "lineNumber": 1, "columnNumber": 2}}},
+			}}, nil
+		}
+		return map[string]interface{}{"type": "success", "result": map[string]interface{}{"type": "undefined"}, "realm": "realm-1"}, nil
+	})
+	ui, _ := start(t, b)
+	for _, method := range []string{"session.new", "session.subscribe", "browsingContext.getTree"} {
+		b.WaitCall(method, nil)
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v, err := ui.Eval(context.Background(), test.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(v.Bytes()) != test.want {
+				t.Errorf("Eval() = %s, want %s", v.Bytes(), test.want)
+			}
+		})
+	}
+	call := b.WaitCall("script.evaluate", func(call gofirefoxtest.Call) bool {
+		return strings.Contains(string(call.Params), `"expression":"string"`)
+	})
+	if call.Target != b.MainTarget() || !strings.Contains(string(call.Params), `"awaitPromise":true`) {
+		t.Errorf("script.evaluate(%s) sent to %q", call.Params, call.Target)
+	}
+
+	_, err := ui.Eval(context.Background(), "throw")
+	jsErr := &gofirefox.JSException{}
+	if !errors.As(err, &jsErr) || jsErr.Text != "Error: boom" || jsErr.URL != "https://example.com/app.js" || jsErr.LineNumber != 1 {
+		t.Errorf("Eval() error = %#v, want the JS exception", err)
+	}
+}
+
+func TestBiDiConsole(t *testing.T) {
+	b := gofirefoxtest.NewBiDi(t, "about:blank")
+	ui, _ := start(t, b)
+	console, exceptions := ui.Console(), ui.Exceptions()
+
+	b.Emit("log.entryAdded", map[string]interface{}{
+		"type": "console", "method": "warn", "level": "warn", "text": "hello 42",
+		"timestamp": 1700000000123.0,
+		"source":    map[string]interface{}{"realm": "realm-1", "context": b.MainTarget()},
+		"args": []interface{}{
+			map[string]interface{}{"type": "string", "value": "hello"},
+			map[string]interface{}{"type": "number", "value": 42},
+		},
+		"stackTrace": map[string]interface{}{"callFrames": []interface{}{
+			map[string]interface{}{"functionName": "f", "url": "https://example.com/app.js", "lineNumber": 9, "columnNumber": 4},
+		}},
+	})
+	// workers are not tabs
+	b.Emit("log.entryAdded", map[string]interface{}{
+		"type": "console", "method": "log", "text": "worker",
+		"source": map[string]interface{}{"realm": "worker-1"},
+	})
+	b.Emit("log.entryAdded", map[string]interface{}{
+		"type": "javascript", "level": "error", "text": "Error: boom",
+		"source": map[string]interface{}{"realm": "realm-1", "context": b.MainTarget()},
+		"stackTrace": map[string]interface{}{"callFrames": []interface{}{
+			map[string]interface{}{"url": "https://example.com/app.js", "lineNumber": 3, "columnNumber": 1},
+		}},
+	})
+
+	select {
+	case msg := <-console:
+		if msg.Type != "warn" || msg.Text != "hello 42" || msg.Tab != b.MainTarget() || len(msg.Args) != 2 || msg.Args[1].Float() != 42 {
+			t.Errorf("ConsoleMessage = %+v", msg)
+		}
+		if msg.URL != "https://example.com/app.js" || msg.LineNumber != 9 || msg.ColumnNumber != 4 {
+			t.Errorf("ConsoleMessage location = %s:%d:%d", msg.URL, msg.LineNumber, msg.ColumnNumber)
+		}
+		if !msg.Timestamp.Equal(time.UnixMilli(1700000000123)) {
+			t.Errorf("ConsoleMessage.Timestamp = %v", msg.Timestamp)
+		}
+	case <-time.After(gofirefoxtest.WaitTimeout):
+		t.Fatal("console message was not received")
+	}
+	select {
+	case e := <-exceptions:
+		if e.Text != "Error: boom" || e.URL != "https://example.com/app.js" || e.LineNumber != 3 {
+			t.Errorf("JSException = %+v", e)
+		}
+	case <-time.After(gofirefoxtest.WaitTimeout):
+		t.Fatal("exception was not received")
+	}
+	select {
+	case msg := <-console:
+		t.Errorf("worker message %q was received", msg.Text)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestBiDiIntercept(t *testing.T) {
+	b := gofirefoxtest.NewBiDi(t, "about:blank")
+	ui, _ := start(t, b)
+
+	_, err := ui.Intercept("https://api.example.com/*", func(r *gofirefox.InterceptedRequest) {
+		r.Fulfill(503, map[string]string{"Content-Type": "application/json"}, []byte(`{"offline":true}`))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ui.Intercept("*.png", func(r *gofirefox.InterceptedRequest) {
+		r.Fail()
+	}, gofirefox.ResourceImage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ui.Intercept("https://example.com/?", func(r *gofirefox.InterceptedRequest) {
+		r.Headers["Authorization"] = "Bearer token"
+		r.ContinueWithHeaders(r.Headers)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// one intercept of all requests per tab
+	call := b.WaitCall("network.addIntercept", nil)
+	if want := `{"contexts":["page-1"],"phases":["beforeRequestSent"]}`; string(call.Params) != want {
+		t.Errorf("network.addIntercept params = %s, want %s", call.Params, want)
+	}
+	if calls := b.Calls("network.addIntercept"); len(calls) != 1 {
+		t.Errorf("network.addIntercept called %d times", len(calls))
+	}
+
+	paused := func(id, url, destination, initiatorType string) {
+		b.Emit("network.beforeRequestSent", map[string]interface{}{
+			"context": b.MainTarget(), "isBlocked": true, "intercepts": []string{"intercept-page-1"},
+			"request": map[string]interface{}{
+				"request": id, "url": url, "method": "GET",
+				"headers":     []interface{}{map[string]interface{}{"name": "Accept", "value": map[string]interface{}{"type": "string", "value": "*/*"}}},
+				"destination": destination, "initiatorType": initiatorType,
+			},
+		})
+	}
+	request := func(id string) func(gofirefoxtest.Call) bool {
+		return func(call gofirefoxtest.Call) bool {
+			return strings.Contains(string(call.Params), `"request":"`+id+`"`)
+		}
+	}
+
+	paused("api", "https://api.example.com/status", "", "xmlhttprequest")
+	call = b.WaitCall("network.provideResponse", request("api"))
+	if want := `{"body":{"type":"base64","value":"eyJvZmZsaW5lIjp0cnVlfQ=="},"headers":[{"name":"Content-Type","value":{"type":"string","value":"application/json"}}],"request":"api","statusCode":503}`; string(call.Params) != want {
+		t.Errorf("network.provideResponse params = %s, want %s", call.Params, want)
+	}
+
+	paused("image", "https://example.com/logo.png", "image", "img")
+	call = b.WaitCall("network.failRequest", request("image"))
+	if want := `{"request":"image"}`; string(call.Params) != want {
+		t.Errorf("network.failRequest params = %s, want %s", call.Params, want)
+	}
+
+	// not an image
+	paused("download", "https://example.com/logo.png", "", "")
+	call = b.WaitCall("network.continueRequest", request("download"))
+	if want := `{"request":"download"}`; string(call.Params) != want {
+		t.Errorf("network.continueRequest params = %s, want %s", call.Params, want)
+	}
+
+	paused("page", "https://example.com/a", "document", "")
+	call = b.WaitCall("network.continueRequest", request("page"))
+	if want := `{"headers":[{"name":"Accept","value":{"type":"string","value":"*/*"}},{"name":"Authorization","value":{"type":"string","value":"Bearer token"}}],"request":"page"}`; string(call.Params) != want {
+		t.Errorf("network.continueRequest params = %s, want %s", call.Params, want)
+	}
+
+	// requests which are not blocked are only reported
+	b.Emit("network.beforeRequestSent", map[string]interface{}{
+		"context": b.MainTarget(), "isBlocked": false,
+		"request": map[string]interface{}{"request": "observed", "url": "https://api.example.com/status", "method": "GET"},
+	})
+	time.Sleep(50 * time.Millisecond)
+	for _, call := range b.Calls("") {
+		if request("observed")(call) {
+			t.Errorf("%s(%s) was sent for a request which is not blocked", call.Method, call.Params)
+		}
+	}
+}
+
+func TestBiDiScreenshot(t *testing.T) {
+	b := gofirefoxtest.NewBiDi(t, "about:blank")
+	b.Handle("browsingContext.captureScreenshot", func(call gofirefoxtest.Call) (interface{}, error) {
+		return map[string]interface{}{"data": "aW1hZ2U="}, nil
+	})
+	b.Handle("script.evaluate", func(call gofirefoxtest.Call) (interface{}, error) {
+		result := map[string]interface{}{"type": "null"}
+		if strings.Contains(string(call.Params), `#banner`) {
+			number := func(v float64) map[string]interface{} { return map[string]interface{}{"type": "number", "value": v} }
+			result = map[string]interface{}{"type": "object", "value": []interface{}{
+				[]interface{}{"x", number(10)},
+				[]interface{}{"y", number(1200)},
+				[]interface{}{"width", number(300)},
+				[]interface{}{"height", number(50)},
+			}}
+		}
+		return map[string]interface{}{"type": "success", "result": result, "realm": "realm-" + call.Target}, nil
+	})
+	ui, _ := start(t, b)
+	ctx := context.Background()
+
+	screenshot := func(t *testing.T, opts gofirefox.ScreenshotOptions) map[string]interface{} {
+		t.Helper()
+		return capture(t, b, "browsingContext.captureScreenshot", "image", func() ([]byte, error) { return ui.Screenshot(ctx, opts) })
+	}
+
+	t.Run("viewport", func(t *testing.T) {
+		params := screenshot(t, gofirefox.ScreenshotOptions{})
+		want := map[string]interface{}{"context": b.MainTarget(), "format": map[string]interface{}{"type": "image/png"}}
+		if !reflect.DeepEqual(params, want) {
+			t.Errorf("browsingContext.captureScreenshot params = %v, want %v", params, want)
+		}
+	})
+	t.Run("JPEG", func(t *testing.T) {
+		params := screenshot(t, gofirefox.ScreenshotOptions{Format: gofirefox.ImageJPEG, Quality: 80})
+		if want := map[string]interface{}{"type": "image/jpeg", "quality": 0.8}; !reflect.DeepEqual(params["format"], want) {
+			t.Errorf("format = %v, want %v", params["format"], want)
+		}
+	})
+	t.Run("full page", func(t *testing.T) {
+		params := screenshot(t, gofirefox.ScreenshotOptions{FullPage: true})
+		if params["origin"] != "document" || params["clip"] != nil {
+			t.Errorf("browsingContext.captureScreenshot(%v)", params)
+		}
+	})
+	t.Run("selector", func(t *testing.T) {
+		params := screenshot(t, gofirefox.ScreenshotOptions{Selector: "#banner"})
+		clip := map[string]interface{}{"type": "box", "x": 10.0, "y": 1200.0, "width": 300.0, "height": 50.0}
+		if params["origin"] != "document" || !reflect.DeepEqual(params["clip"], clip) {
+			t.Errorf("browsingContext.captureScreenshot(%v)", params)
+		}
+	})
+	t.Run("element not found", func(t *testing.T) {
+		if _, err := ui.Screenshot(ctx, gofirefox.ScreenshotOptions{Selector: "#missing"}); !errors.Is(err, gofirefox.ErrElementNotFound) {
+			t.Errorf("Screenshot() = %v, want %v", err, gofirefox.ErrElementNotFound)
+		}
+	})
+}
+
+func TestBiDiTabs(t *testing.T) {
+	b := gofirefoxtest.NewBiDi(t, "about:blank")
+	ui, errc := start(t, b)
+
+	tab, err := ui.NewTab("https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.WaitCall("browsingContext.create", nil)
+	if call := b.WaitCall("browsingContext.navigate", nil); call.Target != tab.ID() {
+		t.Errorf("browsingContext.navigate sent to %q, want %q", call.Target, tab.ID())
+	}
+	if tabs := ui.Tabs(); len(tabs) != 2 || tabs[0].ID() != b.MainTarget() || tabs[1].ID() != tab.ID() {
+		t.Errorf("Tabs() = %v", tabs)
+	}
+
+	if _, err := tab.Eval(context.Background(), "1"); err != nil {
+		t.Fatal(err)
+	}
+	if call := b.WaitCall("script.evaluate", func(call gofirefoxtest.Call) bool { return strings.Contains(string(call.Params), `"expression":"1"`) }); call.Target != tab.ID() {
+		t.Errorf("script.evaluate sent to %q, want %q", call.Target, tab.ID())
+	}
+	if err := tab.Activate(); err != nil {
+		t.Fatal(err)
+	}
+	if call := b.WaitCall("browsingContext.activate", nil); call.Target != tab.ID() {
+		t.Errorf("browsingContext.activate sent to %q, want %q", call.Target, tab.ID())
+	}
+
+	if err := tab.Close(); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "tab was not closed", func() bool { return len(ui.Tabs()) == 1 })
+	if err := tab.Load("about:blank"); !errors.Is(err, gofirefox.ErrTabClosed) {
+		t.Errorf("Load() on closed tab = %v, want %v", err, gofirefox.ErrTabClosed)
+	}
+	select {
+	case err := <-errc:
+		t.Fatalf("Run() = %v after closing a tab", err)
+	default:
+	}
+
+	// closing the main tab ends firefox
+	if err := ui.Tabs()[0].Close(); err != nil {
+		t.Fatal(err)
+	}
+	wait(t, errc)
+	if err := ui.Err(); !errors.Is(err, gofirefox.ErrWindowClosed) {
+		t.Errorf("Err() = %v, want %v", err, gofirefox.ErrWindowClosed)
+	}
+}
+
+func TestBiDiPrintToPDF(t *testing.T) {
+	b := gofirefoxtest.NewBiDi(t, "about:blank")
+	b.Handle("browsingContext.print", func(call gofirefoxtest.Call) (interface{}, error) {
+		return map[string]interface{}{"data": base64.StdEncoding.EncodeToString([]byte("%PDF-1.7"))}, nil
+	})
+	ui, _ := start(t, b)
+
+	printPDF := func(opts gofirefox.PDFOptions) map[string]interface{} {
+		t.Helper()
+		return capture(t, b, "browsingContext.print", "%PDF-1.7", func() ([]byte, error) { return ui.PrintToPDF(context.Background(), opts) })
+	}
+
+	params := printPDF(gofirefox.PDFOptions{})
+	if want := map[string]interface{}{"context": b.MainTarget(), "background": false, "orientation": "portrait"}; !reflect.DeepEqual(params, want) {
+		t.Errorf("browsingContext.print params = %v, want %v", params, want)
+	}
+
+	// inches are converted to centimeters
+	params = printPDF(gofirefox.PDFOptions{
+		Paper:      gofirefox.PaperSize{Width: 10, Height: 5},
+		Margins:    &gofirefox.PDFMargins{Top: 1, Bottom: 0.5},
+		Landscape:  true,
+		Background: true,
+		Scale:      0.5,
+		PageRanges: "1-2, 4",
+	})
+	want := map[string]interface{}{
+		"context":     b.MainTarget(),
+		"background":  true,
+		"orientation": "landscape",
+		"page":        map[string]interface{}{"width": 25.4, "height": 12.7},
+		"margin":      map[string]interface{}{"top": 2.54, "bottom": 1.27, "left": 0.0, "right": 0.0},
+		"scale":       0.5,
+		"pageRanges":  []interface{}{"1-2", "4"},
+	}
+	if !reflect.DeepEqual(params, want) {
+		t.Errorf("browsingContext.print params = %v, want %v", params, want)
+	}
+
+	if _, err := ui.PrintToPDF(context.Background(), gofirefox.PDFOptions{HeaderTemplate: "<span class=title></span>"}); err == nil {
+		t.Error("PrintToPDF() with a header template succeeded with BiDi")
+	}
+}
+
+func TestBiDiNavigationPolicy(t *testing.T) {
+	b := gofirefoxtest.NewBiDi(t, "about:blank")
+	blocked := make(chan gofirefox.BlockedNavigation, 10)
+	ui, _ := start(t, b, gofirefox.WithNavigationPolicy(gofirefox.NavigationPolicy{
+		Allow:     []string{"example.com"},
+		Popups:    gofirefox.PopupBlock,
+		OnBlocked: func(n gofirefox.BlockedNavigation) { blocked <- n },
+	}))
+
+	b.WaitCall("network.addIntercept", nil)
+	paused := func(id, url, destination string) {
+		b.Emit("network.beforeRequestSent", map[string]interface{}{
+			"context": b.MainTarget(), "isBlocked": true, "navigation": "nav-" + id,
+			"request": map[string]interface{}{"request": id, "url": url, "method": "GET", "destination": destination},
+		})
+	}
+	request := func(id string) func(gofirefoxtest.Call) bool {
+		return func(call gofirefoxtest.Call) bool {
+			return strings.Contains(string(call.Params), `"request":"`+id+`"`)
+		}
+	}
+	// blocked before the page is loaded
+	paused("evil", "https://evil.com/", "document")
+	b.WaitCall("network.failRequest", request("evil"))
+	if n := <-blocked; n.URL != "https://evil.com/" {
+		t.Errorf("blocked %s, want https://evil.com/", n.URL)
+	}
+	// frames are not tabs
+	paused("frame", "https://ads.example.org/", "iframe")
+	b.WaitCall("network.continueRequest", request("frame"))
+
+	// opened by the UI
+	tab, err := ui.NewTab("about:blank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	popup := b.OpenPopup(b.MainTarget(), "about:blank")
+	select {
+	case n := <-blocked:
+		if n != (gofirefox.BlockedNavigation{Tab: popup, URL: "about:blank", Popup: true}) {
+			t.Errorf("blocked %+v, want the popup", n)
+		}
+	case <-time.After(gofirefoxtest.WaitTimeout):
+		t.Fatal("popup was not blocked")
+	}
+	b.WaitCall("browsingContext.close", func(call gofirefoxtest.Call) bool { return call.Target == popup })
+	for _, call := range b.Calls("browsingContext.close") {
+		if call.Target == tab.ID() {
+			t.Error("tab opened by the UI was closed")
+		}
+	}
+	eventually(t, "tabs were not listed", func() bool { return len(ui.Tabs()) == 2 })
+	if tabs := ui.Tabs(); tabs[0].ID() != b.MainTarget() || tabs[1].ID() != tab.ID() {
+		t.Errorf("Tabs() = %s, %s", tabs[0].ID(), tabs[1].ID())
+	}
+}
+
+func TestBiDiIdleReset(t *testing.T) {
+	b := gofirefoxtest.NewBiDi(t, "about:blank")
+	ui, _ := start(t, b, gofirefox.WithIdleReset(100*time.Millisecond, "https://example.com/home"))
+	resets := ui.IdleResets()
+
+	b.WaitCall("script.addPreloadScript", func(call gofirefoxtest.Call) bool {
+		return strings.Contains(string(call.Params), "__gofirefox_input__")
+	})
+	payload, _ := json.Marshal(map[string]interface{}{"name": "__gofirefox_input__", "seq": 0, "args": []int{}})
+	b.Emit("log.entryAdded", map[string]interface{}{
+		"type": "console", "method": "debug", "text": "",
+		"source": map[string]interface{}{"realm": "realm-1", "context": b.MainTarget()},
+		"args": []interface{}{
+			map[string]interface{}{"type": "string", "value": "__gofirefox_binding__"},
+			map[string]interface{}{"type": "string", "value": string(payload)},
+		},
+	})
+
+	select {
+	case event := <-resets:
+		// BiDi can only delete the cookies
+		if !errors.Is(event.Err, gofirefox.ErrNotSupported) {
+			t.Errorf("IdleResetEvent.Err = %v, want %v", event.Err, gofirefox.ErrNotSupported)
+		}
+	case <-time.After(gofirefoxtest.WaitTimeout):
+		t.Fatal("session was not reset")
+	}
+	b.WaitCall("storage.deleteCookies", nil)
+	b.WaitCall("browsingContext.close", func(call gofirefoxtest.Call) bool { return call.Target == b.MainTarget() })
+	call := b.WaitCall("browsingContext.navigate", nil)
+	if call.Target == b.MainTarget() || !strings.Contains(string(call.Params), "https://example.com/home") {
+		t.Errorf("browsingContext.navigate(%s) sent to %q, want the new main tab", call.Params, call.Target)
+	}
+}
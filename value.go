@@ -0,0 +1,195 @@
+package gofirefox
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/unikiosk/go-firefox/protocol"
+)
+
+// Value is a result of the JS expression evaluated in the browser.
+type Value interface {
+	// To decodes the JSON value into the given Go value, which is left
+	// untouched if the value is undefined or can't be decoded into it.
+	To(v interface{}) error
+	Float() float64
+	Int() int
+	String() string
+	Bool() bool
+	Object() map[string]Value
+	Array() []Value
+	// Bytes returns the raw JSON encoding of the value.
+	Bytes() []byte
+	// IsUndefined reports whether the expression evaluated to undefined.
+	IsUndefined() bool
+	// IsNull reports whether the expression evaluated to null.
+	IsNull() bool
+}
+
+// CallFrame is a single frame of the JS stack trace.
+type CallFrame struct {
+	FunctionName string `json:"functionName"`
+	URL          string `json:"url"`
+	LineNumber   int    `json:"lineNumber"`
+	ColumnNumber int    `json:"columnNumber"`
+}
+
+// JSException is returned when the evaluated expression throws or the
+// returned promise is rejected.
+type JSException struct {
+	Text         string
+	URL          string
+	LineNumber   int
+	ColumnNumber int
+	StackTrace   []CallFrame
+	// Value is the thrown value, if it is JSON serializable.
+	Value json.RawMessage
+	// Description is the string representation of the thrown value, e.g.
+	// "TypeError: x is undefined".
+	Description string
+}
+
+func (e *JSException) Error() string {
+	msg := e.Description
+	if msg == "" && len(e.Value) > 0 {
+		msg = string(e.Value)
+	}
+	if msg == "" {
+		msg = e.Text
+	}
+	if e.URL != "" || e.LineNumber != 0 {
+		return fmt.Sprintf("js exception at %s:%d:%d: %s", e.URL, e.LineNumber, e.ColumnNumber, msg)
+	}
+	return "js exception: " + msg
+}
+
+// newException returns the exception described by the CDP exception details.
+func newException(d *protocol.RuntimeExceptionDetails) *JSException {
+	e := &JSException{
+		Text:         d.Text,
+		URL:          d.URL,
+		LineNumber:   d.LineNumber,
+		ColumnNumber: d.ColumnNumber,
+		StackTrace:   callFrames(d.StackTrace),
+	}
+	if d.Exception != nil {
+		e.Description = d.Exception.Description
+		if len(d.Exception.Value) > 0 && string(d.Exception.Value) != "null" {
+			e.Value = d.Exception.Value
+		}
+	}
+	return e
+}
+
+// callFrames returns the frames of the CDP stack trace, if any.
+func callFrames(st *protocol.RuntimeStackTrace) []CallFrame {
+	if st == nil {
+		return nil
+	}
+	frames := make([]CallFrame, 0, len(st.CallFrames))
+	for _, f := range st.CallFrames {
+		frames = append(frames, CallFrame{
+			FunctionName: f.FunctionName,
+			URL:          f.URL,
+			LineNumber:   f.LineNumber,
+			ColumnNumber: f.ColumnNumber,
+		})
+	}
+	return frames
+}
+
+// value is a Value implementation backed by the JSON encoded remote value.
+type value struct {
+	undefined bool
+	raw       json.RawMessage
+}
+
+func newValue(o protocol.RuntimeRemoteObject) *value {
+	switch {
+	case o.Type == "undefined":
+		return &value{undefined: true}
+	case o.UnserializableValue != "":
+		// NaN, Infinity, -Infinity, -0 and BigInt literals can't be
+		// represented in JSON, keep their textual form as a string.
+		b, _ := json.Marshal(o.UnserializableValue)
+		return &value{raw: b}
+	case len(o.Value) == 0:
+		// Objects that can't be returned by value (e.g. DOM nodes) have no
+		// value, so fall back to their description.
+		if o.Type == "object" && o.Subtype == "null" {
+			return &value{raw: json.RawMessage("null")}
+		}
+		b, _ := json.Marshal(o.Description)
+		return &value{raw: b}
+	default:
+		return &value{raw: o.Value}
+	}
+}
+
+func (v *value) To(x interface{}) error {
+	if v.undefined {
+		return nil
+	}
+	if rv := reflect.ValueOf(x); rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		// decoding stops at the first error, with x partially set, so the
+		// value is checked first
+		if err := json.Unmarshal(v.raw, reflect.New(rv.Type().Elem()).Interface()); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(v.raw, x)
+}
+
+func (v *value) Float() (f float64) {
+	var s string
+	if err := v.To(&s); err == nil {
+		// unserializable numbers are kept in their textual form
+		f, _ = strconv.ParseFloat(strings.TrimSuffix(s, "n"), 64)
+		return f
+	}
+	v.To(&f)
+	return f
+}
+
+func (v *value) Int() (i int) {
+	v.To(&i)
+	return i
+}
+
+func (v *value) String() (s string) {
+	v.To(&s)
+	return s
+}
+
+func (v *value) Bool() (b bool) {
+	v.To(&b)
+	return b
+}
+
+func (v *value) Bytes() []byte { return v.raw }
+
+func (v *value) IsUndefined() bool { return v.undefined }
+
+func (v *value) IsNull() bool { return !v.undefined && string(v.raw) == "null" }
+
+func (v *value) Array() (values []Value) {
+	array := []json.RawMessage{}
+	v.To(&array)
+	for _, el := range array {
+		values = append(values, &value{raw: el})
+	}
+	return values
+}
+
+func (v *value) Object() (object map[string]Value) {
+	object = map[string]Value{}
+	kv := map[string]json.RawMessage{}
+	v.To(&kv)
+	for k, v := range kv {
+		object[k] = &value{raw: v}
+	}
+	return object
+}
@@ -0,0 +1,106 @@
+package gofirefox
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/unikiosk/go-firefox/protocol"
+)
+
+func TestNewValue(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		object    protocol.RuntimeRemoteObject
+		bytes     string
+		undefined bool
+		null      bool
+		str       string
+		float     float64
+	}{
+		{name: "undefined", object: protocol.RuntimeRemoteObject{Type: "undefined"}, undefined: true},
+		{name: "null", object: protocol.RuntimeRemoteObject{Type: "object", Subtype: "null", Value: json.RawMessage("null")}, bytes: "null", null: true},
+		{name: "null without value", object: protocol.RuntimeRemoteObject{Type: "object", Subtype: "null"}, bytes: "null", null: true},
+		{name: "string", object: protocol.RuntimeRemoteObject{Type: "string", Value: json.RawMessage(`"hi"`)}, bytes: `"hi"`, str: "hi"},
+		{name: "number", object: protocol.RuntimeRemoteObject{Type: "number", Value: json.RawMessage("1.5")}, bytes: "1.5", float: 1.5},
+		{name: "NaN", object: protocol.RuntimeRemoteObject{Type: "number", UnserializableValue: "NaN"}, bytes: `"NaN"`, str: "NaN", float: math.NaN()},
+		{name: "Infinity", object: protocol.RuntimeRemoteObject{Type: "number", UnserializableValue: "Infinity"}, bytes: `"Infinity"`, str: "Infinity", float: math.Inf(1)},
+		{name: "-Infinity", object: protocol.RuntimeRemoteObject{Type: "number", UnserializableValue: "-Infinity"}, bytes: `"-Infinity"`, str: "-Infinity", float: math.Inf(-1)},
+		{name: "-0", object: protocol.RuntimeRemoteObject{Type: "number", UnserializableValue: "-0"}, bytes: `"-0"`, str: "-0", float: math.Copysign(0, -1)},
+		{name: "bigint", object: protocol.RuntimeRemoteObject{Type: "bigint", UnserializableValue: "12345678901234567890n"}, bytes: `"12345678901234567890n"`, str: "12345678901234567890n", float: 12345678901234567890},
+		{name: "DOM node", object: protocol.RuntimeRemoteObject{Type: "object", Subtype: "node", ClassName: "HTMLDivElement", Description: "div#main"}, bytes: `"div#main"`, str: "div#main"},
+		{name: "function", object: protocol.RuntimeRemoteObject{Type: "function", Description: "function f() {}"}, bytes: `"function f() {}"`, str: "function f() {}"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			v := newValue(test.object)
+			if string(v.Bytes()) != test.bytes {
+				t.Errorf("Bytes() = %s, want %s", v.Bytes(), test.bytes)
+			}
+			if v.IsUndefined() != test.undefined || v.IsNull() != test.null {
+				t.Errorf("IsUndefined() = %v, IsNull() = %v, want %v, %v", v.IsUndefined(), v.IsNull(), test.undefined, test.null)
+			}
+			if s := v.String(); s != test.str {
+				t.Errorf("String() = %q, want %q", s, test.str)
+			}
+			f := v.Float()
+			if f != test.float && !(math.IsNaN(f) && math.IsNaN(test.float)) || math.Signbit(f) != math.Signbit(test.float) {
+				t.Errorf("Float() = %v, want %v", f, test.float)
+			}
+		})
+	}
+}
+
+func TestValueWrongType(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		raw    string
+		array  int
+		object int
+	}{
+		{name: "array", raw: `[1, 2]`, array: 2},
+		{name: "object", raw: `{"a": 1}`, object: 1},
+		{name: "number", raw: `1`},
+		{name: "string", raw: `"a"`},
+		{name: "null", raw: `null`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			v := &value{raw: json.RawMessage(test.raw)}
+			if n := len(v.Array()); n != test.array {
+				t.Errorf("len(Array()) = %d, want %d", n, test.array)
+			}
+			if object := v.Object(); object == nil || len(object) != test.object {
+				t.Errorf("Object() = %v, want %d entries", object, test.object)
+			}
+		})
+	}
+}
+
+func TestValueTo(t *testing.T) {
+	type point struct {
+		X, Y int
+	}
+	for _, test := range []struct {
+		name string
+		v    *value
+		want point
+		err  bool
+	}{
+		{name: "object", v: &value{raw: json.RawMessage(`{"X": 3, "Y": 4}`)}, want: point{3, 4}},
+		{name: "undefined", v: &value{undefined: true}, want: point{1, 2}},
+		{name: "null", v: &value{raw: json.RawMessage("null")}, want: point{1, 2}},
+		{name: "wrong type", v: &value{raw: json.RawMessage(`"a"`)}, want: point{1, 2}, err: true},
+		{name: "wrong field type", v: &value{raw: json.RawMessage(`{"X": 3, "Y": "a"}`)}, want: point{1, 2}, err: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			out := point{1, 2}
+			err := test.v.To(&out)
+			if (err != nil) != test.err {
+				t.Errorf("To() error = %v", err)
+			}
+			if !reflect.DeepEqual(out, test.want) {
+				t.Errorf("To() = %+v, want %+v", out, test.want)
+			}
+		})
+	}
+}
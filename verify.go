@@ -0,0 +1,136 @@
+package gofirefox
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// ErrProfileVerification is returned when the downloaded user.js doesn't
+// match the configured checksum or signature.
+var ErrProfileVerification = errors.New("gofirefox: profile verification failed")
+
+// profileVerifier verifies the downloaded user.js with the SHA-256 checksum
+// and/or the minisign signature. A nil verifier accepts everything.
+type profileVerifier struct {
+	sha256    []byte
+	publicKey *minisignKey
+}
+
+func newProfileVerifier(sha256Hex, publicKey string) (*profileVerifier, error) {
+	if sha256Hex == "" && publicKey == "" {
+		return nil, nil
+	}
+	v := &profileVerifier{}
+	if sha256Hex != "" {
+		sum, err := hex.DecodeString(strings.TrimSpace(sha256Hex))
+		if err != nil || len(sum) != sha256.Size {
+			return nil, fmt.Errorf("invalid user.js SHA-256 checksum %q", sha256Hex)
+		}
+		v.sha256 = sum
+	}
+	if publicKey != "" {
+		key, err := parseMinisignKey(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		v.publicKey = key
+	}
+	return v, nil
+}
+
+// needsSignature reports whether the signature must be downloaded along with
+// the document.
+func (v *profileVerifier) needsSignature() bool {
+	return v != nil && v.publicKey != nil
+}
+
+func (v *profileVerifier) verify(data, signature []byte) error {
+	if v == nil {
+		return nil
+	}
+	if v.sha256 != nil {
+		if sum := sha256.Sum256(data); !bytes.Equal(sum[:], v.sha256) {
+			return fmt.Errorf("%w: SHA-256 checksum %x doesn't match", ErrProfileVerification, sum)
+		}
+	}
+	if v.publicKey != nil {
+		if err := v.publicKey.verify(data, signature); err != nil {
+			return fmt.Errorf("%w: %s", ErrProfileVerification, err)
+		}
+	}
+	return nil
+}
+
+/* minisign (https://jedisct1.github.io/minisign/) public keys and signatures are
+base64 encoded: the key is "Ed" followed by the 8 byte key ID and the Ed25519 public key,
+the signature is the algorithm followed by the key ID and the Ed25519 signature, and it is
+followed by the trusted comment and the global signature of the signature and the comment.
+The legacy "Ed" algorithm signs the document itself, the default "ED" algorithm signs its
+BLAKE2b-512 hash. */
+
+type minisignKey struct {
+	id  [8]byte
+	key ed25519.PublicKey
+}
+
+// parseMinisignKey parses the base64 public key, or the content of the
+// minisign public key file.
+func parseMinisignKey(s string) (*minisignKey, error) {
+	b, err := base64.StdEncoding.DecodeString(lastLine(s))
+	if err != nil || len(b) != 2+8+ed25519.PublicKeySize || string(b[:2]) != "Ed" {
+		return nil, fmt.Errorf("invalid minisign public key")
+	}
+	k := &minisignKey{key: ed25519.PublicKey(b[10:])}
+	copy(k.id[:], b[2:10])
+	return k, nil
+}
+
+func (k *minisignKey) verify(data, signature []byte) error {
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(string(signature), "\r\n", "\n")), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return errors.New("invalid minisign signature")
+	}
+	sig, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(sig) != 2+8+ed25519.SignatureSize {
+		return errors.New("invalid minisign signature")
+	}
+	signed := data
+	switch string(sig[:2]) {
+	case "Ed":
+	case "ED":
+		sum := blake2b.Sum512(data)
+		signed = sum[:]
+	default:
+		return fmt.Errorf("unknown minisign signature algorithm %q", sig[:2])
+	}
+	if !bytes.Equal(sig[2:10], k.id[:]) {
+		return fmt.Errorf("signature key ID %X doesn't match the public key %X", sig[2:10], k.id)
+	}
+	if !ed25519.Verify(k.key, signed, sig[10:]) {
+		return errors.New("invalid signature")
+	}
+
+	globalSig, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil {
+		return errors.New("invalid minisign global signature")
+	}
+	comment := strings.TrimPrefix(lines[2], "trusted comment: ")
+	signed = append(append([]byte{}, sig[10:]...), comment...)
+	if !ed25519.Verify(k.key, signed, globalSig) {
+		return errors.New("invalid trusted comment signature")
+	}
+	return nil
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}